@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Loads one administrative layer (police sectors, council districts, ...)
+// from a GeoJSON FeatureCollection of Polygon or MultiPolygon features, then
+// assigns every address to its area in that layer. Features already loaded
+// under the same name get their boundary replaced.
+func readAreas(p *pgxpool.Pool, layer, path, nameProperty string) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("cant read file %s\nerr: %s\n", path, err)
+	}
+
+	var collection struct {
+		Features []struct {
+			Geometry   json.RawMessage `json:"geometry"`
+			Properties map[string]any  `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(dat, &collection); err != nil {
+		log.Fatalf("cant parse %s\nerr: %s\n", path, err)
+	}
+
+	var layerID int64
+	sql := `
+	INSERT INTO area_layers (layer_name)
+	VALUES ($1)
+	ON CONFLICT (layer_name) DO UPDATE SET layer_name = EXCLUDED.layer_name
+	RETURNING layer_id
+	`
+	if err := p.QueryRow(context.Background(), sql, layer).Scan(&layerID); err != nil {
+		log.Fatalf("cant create layer %s\nerr: %s\n", layer, err)
+	}
+
+	sql = `
+	INSERT INTO admin_areas (layer_id, area_name, boundary)
+	VALUES ($1, $2, ST_Multi(ST_SetSRID(ST_GeomFromGeoJSON($3), 4326))::geography)
+	ON CONFLICT (layer_id, area_name) DO UPDATE SET boundary = EXCLUDED.boundary
+	`
+	loaded := 0
+	for i, f := range collection.Features {
+		name, ok := lookupFold(f.Properties, nameProperty)
+		if !ok || name == nil {
+			log.Printf("skipping feature %d, no %s property", i, nameProperty)
+			continue
+		}
+		areaName := strings.TrimSpace(fmt.Sprint(name))
+
+		var geometry struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(f.Geometry, &geometry); err != nil || (geometry.Type != "Polygon" && geometry.Type != "MultiPolygon") {
+			log.Printf("skipping %s, not a polygon", areaName)
+			continue
+		}
+
+		if _, err := p.Exec(context.Background(), sql, layerID, areaName, string(f.Geometry)); err != nil {
+			log.Printf("error inserting %s: %s", areaName, err)
+			continue
+		}
+		loaded++
+	}
+	log.Printf("loaded %d areas into %s\n", loaded, layer)
+
+	var assigned int
+	if err := p.QueryRow(context.Background(), "SELECT assign_address_areas($1)", layerID).Scan(&assigned); err != nil {
+		log.Fatalf("cant assign addresses to %s\nerr: %s\n", layer, err)
+	}
+	log.Printf("assigned %d addresses\n", assigned)
+}
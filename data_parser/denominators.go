@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Loads population and land area for cities and neighborhoods from a csv with
+// a header row. Columns are found by name: name (or NAME as census exports
+// call it), population, area_sq_mi, and an optional level of city or
+// neighborhood, neighborhood when missing. Empty cells leave the value as is.
+func readDenominators(p *pgxpool.Pool, path string) {
+	dat, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("cant read file %s\nerr: %s\n", path, err)
+	}
+	defer dat.Close()
+
+	reader := csv.NewReader(dat)
+	header, err := reader.Read()
+	if err != nil {
+		log.Fatalf("cant read header of %s\nerr: %s\n", path, err)
+	}
+
+	cols := headerIndex(header)
+	nameCol, ok := cols["name"]
+	if !ok {
+		log.Fatalln("denominator csv needs a name column")
+	}
+	popCol, hasPop := cols["population"]
+	areaCol, hasArea := cols["area_sq_mi"]
+	levelCol, hasLevel := cols["level"]
+	if !hasPop && !hasArea {
+		log.Fatalln("denominator csv needs a population or area_sq_mi column")
+	}
+
+	field := func(record []string, col int, ok bool) *string {
+		if !ok || col >= len(record) {
+			return nil
+		}
+		v := strings.ReplaceAll(strings.TrimSpace(record[col]), ",", "")
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+
+	updated := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		level, table, column := "neighborhood", "neighborhoods", "neighborhood_name"
+		if l := field(record, levelCol, hasLevel); l != nil && strings.EqualFold(*l, "city") {
+			level, table, column = "city", "cities", "city_name"
+		}
+
+		var population *int
+		if v := field(record, popCol, hasPop); v != nil {
+			n, err := strconv.Atoi(*v)
+			if err != nil || n <= 0 {
+				log.Printf("skipping %s, bad population %q", record[nameCol], *v)
+				continue
+			}
+			population = &n
+		}
+		var area *float64
+		if v := field(record, areaCol, hasArea); v != nil {
+			a, err := strconv.ParseFloat(*v, 64)
+			if err != nil || a <= 0 {
+				log.Printf("skipping %s, bad area %q", record[nameCol], *v)
+				continue
+			}
+			area = &a
+		}
+
+		sql := `
+		UPDATE ` + table + `
+		SET population = COALESCE($2, population),
+			area_sq_mi = COALESCE($3, area_sq_mi),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE LOWER(` + column + `) = LOWER($1)
+		`
+		tag, err := p.Exec(context.Background(), sql, strings.TrimSpace(record[nameCol]), population, area)
+		if err != nil {
+			log.Printf("error updating %s: %s", record[nameCol], err)
+			continue
+		}
+		if tag.RowsAffected() == 0 {
+			log.Printf("no %s named %s", level, record[nameCol])
+			continue
+		}
+		updated++
+	}
+	log.Printf("updated denominators for %d areas\n", updated)
+}
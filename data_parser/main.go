@@ -30,6 +30,24 @@ func main() {
 	log.Println("Connected to db!")
 	defer p.Close()
 
+	// census populations and land areas don't need geocoding
+	if len(os.Args) > 2 && os.Args[1] == "denominators" {
+		readDenominators(p, os.Args[2])
+		os.Exit(0)
+	}
+	if len(os.Args) > 3 && os.Args[1] == "pois" {
+		readPOIs(p, os.Args[2], os.Args[3])
+		os.Exit(0)
+	}
+	if len(os.Args) > 3 && os.Args[1] == "areas" {
+		nameProperty := "name"
+		if len(os.Args) > 4 {
+			nameProperty = os.Args[4]
+		}
+		readAreas(p, os.Args[2], os.Args[3], nameProperty)
+		os.Exit(0)
+	}
+
 	log.Println(os.Getenv("MAPS_API"))
 	c, err := maps.NewClient(maps.WithAPIKey(os.Getenv("MAPS_API")))
 	if err != nil {
@@ -55,6 +73,7 @@ func readCSV(p *pgxpool.Pool, c *maps.Client, path string) {
 
 	reader := csv.NewReader(dat)
 	count := 0
+	statusCol := -1
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
@@ -65,9 +84,11 @@ func readCSV(p *pgxpool.Pool, c *maps.Client, path string) {
 		}
 		// fmt.Println(record)
 
-		if count != 0 {
+		if count == 0 {
+			statusCol = statusColumn(record)
+		} else {
 			// log.Println(len(record))
-			insert(p, c, record)
+			insert(p, c, record, statusCol)
 			// for i := 0; i < 10; i++ {
 			// 	_, err = reader.Read()
 			// 	if err == io.EOF {
@@ -80,9 +101,14 @@ func readCSV(p *pgxpool.Pool, c *maps.Client, path string) {
 		count++
 	}
 	// log.Println(records)
+
+	// nearest neighborhoods read the centers of the addresses
+	if _, err := p.Exec(context.Background(), "REFRESH MATERIALIZED VIEW CONCURRENTLY neighborhood_centers"); err != nil {
+		log.Printf("error refreshing neighborhood centers: %s\n", err)
+	}
 }
 
-func insert(p *pgxpool.Pool, c *maps.Client, record []string) {
+func insert(p *pgxpool.Pool, c *maps.Client, record []string, statusCol int) {
 	caseno := record[0]
 	lat := record[1]
 	lon := record[2]
@@ -91,6 +117,10 @@ func insert(p *pgxpool.Pool, c *maps.Client, record []string) {
 	date := record[5]
 	time := record[6]
 	neighborhood := record[8]
+	resolved := false
+	if statusCol >= 0 && statusCol < len(record) {
+		resolved = isResolved(record[statusCol])
+	}
 
 	// TODO:
 	// if redacted continue
@@ -149,11 +179,12 @@ func insert(p *pgxpool.Pool, c *maps.Client, record []string) {
 								  p_neighborhood := $13,
 	                              p_incident_date := $10::date,
 	                              p_incident_time := $11::time,
-	                              p_case_num := $12)`
+	                              p_case_num := $12,
+	                              p_is_resolved := $14)`
 
 	_, err = tx.Exec(context.Background(), sql,
 		"Tacoma", "Washington", "United States", "City of Tacoma Reported Crime (Tacoma)",
-		lat, lon, address, zip, category, date, time, caseno, neighborhood)
+		lat, lon, address, zip, category, date, time, caseno, neighborhood, resolved)
 
 	if err != nil {
 		log.Printf("errr rolling back! %s", err)
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type poi struct {
+	name      string
+	latitude  float64
+	longitude float64
+}
+
+// Column and property names tried in order, covering GTFS stops.txt and
+// common open data exports
+var (
+	poiNameKeys = []string{"name", "stop_name", "title", "facility_name", "park_name"}
+	poiLatKeys  = []string{"latitude", "lat", "stop_lat", "y"}
+	poiLngKeys  = []string{"longitude", "lng", "lon", "long", "stop_lon", "x"}
+)
+
+// Loads points of interest of one type (transit, parking, bar, park, ...)
+// from a GeoJSON file of Point features or a csv with a header row
+func readPOIs(p *pgxpool.Pool, poiType, path string) {
+	var pois []poi
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".geojson", ".json":
+		pois, err = readPOIGeoJSON(path)
+	default:
+		pois, err = readPOICSV(path)
+	}
+	if err != nil {
+		log.Fatalf("cant read pois from %s\nerr: %s\n", path, err)
+	}
+
+	sql := `
+	INSERT INTO pois (poi_name, poi_type, latitude, longitude, source)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (poi_type, poi_name, latitude, longitude) DO NOTHING
+	`
+	inserted := 0
+	for _, poi := range pois {
+		tag, err := p.Exec(context.Background(), sql, poi.name, poiType, poi.latitude, poi.longitude, filepath.Base(path))
+		if err != nil {
+			log.Printf("error inserting %s: %s", poi.name, err)
+			continue
+		}
+		inserted += int(tag.RowsAffected())
+	}
+	log.Printf("inserted %d of %d %s pois\n", inserted, len(pois), poiType)
+}
+
+func readPOIGeoJSON(path string) ([]poi, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(dat, &collection); err != nil {
+		return nil, err
+	}
+
+	var pois []poi
+	for i, f := range collection.Features {
+		if f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) < 2 {
+			log.Printf("skipping feature %d, not a point", i)
+			continue
+		}
+		name := ""
+		for _, key := range poiNameKeys {
+			if v, ok := lookupFold(f.Properties, key); ok {
+				name = strings.TrimSpace(fmt.Sprint(v))
+				break
+			}
+		}
+		if name == "" {
+			log.Printf("skipping feature %d, no name", i)
+			continue
+		}
+		pois = append(pois, poi{name: name, longitude: f.Geometry.Coordinates[0], latitude: f.Geometry.Coordinates[1]})
+	}
+	return pois, nil
+}
+
+func readPOICSV(path string) ([]poi, error) {
+	dat, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dat.Close()
+
+	reader := csv.NewReader(dat)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	cols := headerIndex(header)
+	nameCol, okName := findColumn(cols, poiNameKeys)
+	latCol, okLat := findColumn(cols, poiLatKeys)
+	lngCol, okLng := findColumn(cols, poiLngKeys)
+	if !okName || !okLat || !okLng {
+		return nil, fmt.Errorf("csv needs name, latitude and longitude columns")
+	}
+
+	var pois []poi
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(record[latCol]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(record[lngCol]), 64)
+		name := strings.TrimSpace(record[nameCol])
+		if errLat != nil || errLng != nil || name == "" {
+			log.Printf("skipping row %v", record)
+			continue
+		}
+		pois = append(pois, poi{name: name, latitude: lat, longitude: lng})
+	}
+	return pois, nil
+}
+
+// Lowercased header names to their column, without a leading BOM. The first
+// of any duplicate names wins.
+func headerIndex(header []string) map[string]int {
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := cols[name]; !ok {
+			cols[name] = i
+		}
+	}
+	return cols
+}
+
+// Column of the first of keys in the header
+func findColumn(cols map[string]int, keys []string) (int, bool) {
+	for _, key := range keys {
+		if i, ok := cols[strings.ToLower(key)]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// GeoJSON property lookup ignoring case, an exact match wins over the others
+// and those are tried in sorted order so the pick is stable
+func lookupFold(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return m[k], true
+		}
+	}
+	return nil, false
+}
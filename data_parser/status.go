@@ -0,0 +1,37 @@
+package main
+
+import "strings"
+
+// Header names for the case status or disposition column, tried in order
+var statusKeys = []string{"status", "case_status", "disposition", "case_disposition", "clearance", "clearance_status"}
+
+// Dispositions meaning the case is still open, checked before resolvedWords so
+// negations like "not cleared", "no arrest" or "unresolved" and "open - pending
+// arrest" stay unresolved
+var unresolvedWords = []string{"open", "pending", "uncleared", "unresolved", "unsolved", "not ", "no arrest", "unfounded", "inactive"}
+
+var resolvedWords = []string{"closed", "cleared", "arrest", "exceptional", "resolved", "solved", "cited", "citation"}
+
+// Index of the status column in a header row, -1 when the export has none
+func statusColumn(header []string) int {
+	if i, ok := findColumn(headerIndex(header), statusKeys); ok {
+		return i
+	}
+	return -1
+}
+
+// Maps a source's status or disposition text to is_resolved
+func isResolved(status string) bool {
+	status = strings.ToLower(strings.TrimSpace(status))
+	for _, word := range unresolvedWords {
+		if strings.Contains(status, word) {
+			return false
+		}
+	}
+	for _, word := range resolvedWords {
+		if strings.Contains(status, word) {
+			return true
+		}
+	}
+	return false
+}
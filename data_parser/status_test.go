@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestIsResolved(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{"", false},
+		{"Closed", true},
+		{"CLEARED BY ARREST", true},
+		{"Arrest", true},
+		{"Exceptionally Cleared", true},
+		{"Citation Issued", true},
+		{"No Arrest", false},
+		{"Not Cleared", false},
+		{"not solved", false},
+		{"Unresolved", false},
+		{"Open - Pending Arrest", false},
+		{"Inactive", false},
+		{"Unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := isResolved(tt.status); got != tt.want {
+				t.Errorf("isResolved(%q) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package public
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Incident count for one category at one location
+type locationCount struct {
+	Latitude  float64
+	Longitude float64
+	Category  string
+	Count     int
+}
+
+type HexBin struct {
+	Cell       string        `json:"cell"`
+	Parent     string        `json:"parent,omitempty"`
+	Resolution int           `json:"resolution"`
+	Count      int           `json:"count"`
+	Categories []OrderedPair `json:"categories"`
+	cell       HexCell
+}
+
+type GeoJSONFeature struct {
+	Type       string         `json:"type"`
+	Geometry   map[string]any `json:"geometry"`
+	Properties any            `json:"properties"`
+}
+
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+func newFeatureCollection() GeoJSONFeatureCollection {
+	return GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{}}
+}
+
+func polygonFeature(polygon Polygon, properties any) GeoJSONFeature {
+	return GeoJSONFeature{
+		Type: "Feature",
+		Geometry: map[string]any{
+			"type":        "Polygon",
+			"coordinates": polygon,
+		},
+		Properties: properties,
+	}
+}
+
+// Grouping by location keeps the row count down to distinct addresses
+func (h *Handler) getLocationCounts(filters CrimeFilters) ([]locationCount, error) {
+	query := `
+		SELECT
+			l.latitude::float8,
+			l.longitude::float8,
+			COALESCE(cc.category_name, 'Other') as category_name,
+			COUNT(*) as count
+		` + crimeDumpFrom
+	query, args := filters.appendWhere(query, []any{})
+	query += " GROUP BY l.location_id, l.latitude, l.longitude, cc.category_name"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying location counts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []locationCount
+	for rows.Next() {
+		var lc locationCount
+		if err := rows.Scan(&lc.Latitude, &lc.Longitude, &lc.Category, &lc.Count); err != nil {
+			log.Printf("Error scanning location count: %v", err)
+			continue
+		}
+		counts = append(counts, lc)
+	}
+	return counts, nil
+}
+
+// Hexagon bin counts as a GeoJSON FeatureCollection.
+// Cells at one resolution have equal area so neighborhoods compare fairly.
+func (h *Handler) GetHexBins(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res := 8
+	if resStr := c.Query("resolution"); resStr != "" {
+		res, err = strconv.Atoi(resStr)
+		if err != nil || res < HEX_MIN_RES || res > HEX_MAX_RES {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resolution must be between 0 and 12"})
+			return
+		}
+	}
+
+	counts, err := h.getLocationCounts(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to aggregate hexagon bins",
+		})
+		return
+	}
+
+	bins := binHexagons(counts, res)
+
+	cells := newFeatureCollection()
+	total := 0
+	for _, bin := range bins {
+		cells.Features = append(cells.Features, polygonFeature(Polygon{bin.cell.Boundary()}, bin))
+		total += bin.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cells":         cells,
+		"resolution":    res,
+		"edge_length_m": hexEdgeMeters(res),
+		"cell_area_km2": hexAreaKm2(res),
+		"total_cells":   len(bins),
+		"total_crimes":  total,
+		"year":          filters.Years,
+		"crime_type":    filters.CrimeTypes,
+		"neighborhood":  filters.Neighborhoods,
+	})
+}
+
+func binHexagons(counts []locationCount, res int) []HexBin {
+	type accumulator struct {
+		cell       HexCell
+		count      int
+		categories map[string]int
+	}
+
+	cells := make(map[HexCell]*accumulator)
+	for _, lc := range counts {
+		cell := hexCellAt(lc.Latitude, lc.Longitude, res)
+		acc, ok := cells[cell]
+		if !ok {
+			acc = &accumulator{cell: cell, categories: make(map[string]int)}
+			cells[cell] = acc
+		}
+		acc.count += lc.Count
+		acc.categories[lc.Category] += lc.Count
+	}
+
+	bins := make([]HexBin, 0, len(cells))
+	for _, acc := range cells {
+		bin := HexBin{
+			Cell:       acc.cell.ID(),
+			Resolution: res,
+			Count:      acc.count,
+			Categories: sortedPairs(acc.categories),
+			cell:       acc.cell,
+		}
+		if parent, ok := acc.cell.Parent(); ok {
+			bin.Parent = parent.ID()
+		}
+		bins = append(bins, bin)
+	}
+
+	sort.Slice(bins, func(i, j int) bool {
+		if bins[i].Count != bins[j].Count {
+			return bins[i].Count > bins[j].Count
+		}
+		return bins[i].Cell < bins[j].Cell
+	})
+	return bins
+}
+
+// Largest first, ties by key
+func sortedPairs(m map[string]int) []OrderedPair {
+	pairs := make([]OrderedPair, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, OrderedPair{Key: k, Value: v})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Value != pairs[j].Value {
+			return pairs[i].Value > pairs[j].Value
+		}
+		return pairs[i].Key < pairs[j].Key
+	})
+	return pairs
+}
+
+// Kernel density surface. kernel=gaussian|quartic, bandwidth and cell_size in
+// meters, output=grid|contours. Takes the same filters as the heat map.
+func (h *Handler) GetCrimeDensity(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kernel, ok := densityKernels[c.DefaultQuery("kernel", "quartic")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kernel must be gaussian or quartic"})
+		return
+	}
+
+	bandwidth, err := strconv.ParseFloat(c.DefaultQuery("bandwidth", "300"), 64)
+	if err != nil || bandwidth < 25 || bandwidth > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bandwidth must be between 25 and 5000 meters"})
+		return
+	}
+
+	cellSize, err := strconv.ParseFloat(c.DefaultQuery("cell_size", "50"), 64)
+	if err != nil || cellSize < 10 || cellSize > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cell_size must be between 10 and 1000 meters"})
+		return
+	}
+
+	output := c.DefaultQuery("output", "grid")
+	if output != "grid" && output != "contours" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "output must be grid or contours"})
+		return
+	}
+
+	levelCount, err := strconv.Atoi(c.DefaultQuery("levels", "5"))
+	if err != nil || levelCount < 1 || levelCount > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "levels must be between 1 and 20"})
+		return
+	}
+
+	counts, err := h.getLocationCounts(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to estimate crime density",
+		})
+		return
+	}
+
+	response := gin.H{
+		"kernel":      kernel.name,
+		"bandwidth_m": bandwidth,
+		"units":       "incidents per square kilometer",
+		"year":        filters.Years,
+		"crime_type":  filters.CrimeTypes,
+	}
+
+	grid := estimateDensity(counts, kernel, bandwidth, cellSize)
+	response["cell_size_m"] = grid.CellSize
+	response["max_density"] = grid.Max
+
+	if output == "grid" {
+		response["grid"] = grid
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// evenly spaced levels, rings are emitted lowest level first so
+	// drawing them in order stacks the higher densities on top
+	contours := newFeatureCollection()
+	levels := make([]float64, levelCount)
+	for i := range levels {
+		levels[i] = grid.Max * float64(i+1) / float64(levelCount+1)
+		for _, polygon := range grid.Contours(levels[i]) {
+			contours.Features = append(contours.Features, polygonFeature(polygon, gin.H{
+				"level":   i + 1,
+				"density": levels[i],
+			}))
+		}
+	}
+	response["levels"] = levels
+	response["contours"] = contours
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) getHourMatrix(filters CrimeFilters) (HourMatrix, error) {
+	matrix := HourMatrix{Days: isoWeekdays}
+
+	query := `
+		SELECT
+			EXTRACT(ISODOW FROM ci.incident_date)::int as dow,
+			EXTRACT(HOUR FROM ci.incident_time)::int as hour,
+			COUNT(*) as count
+		` + crimeDumpFrom
+	query, args := filters.appendWhere(query, []any{})
+	query += " GROUP BY dow, hour"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying hour matrix: %v", err)
+		return matrix, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dow, count int
+		var hour *int
+		if err := rows.Scan(&dow, &hour, &count); err != nil {
+			log.Printf("Error scanning hour matrix: %v", err)
+			continue
+		}
+		day := dow - 1
+		if hour == nil {
+			matrix.UnknownTime[day] += count
+		} else {
+			matrix.Counts[day][*hour] += count
+			matrix.DayTotals[day] += count
+			matrix.HourTotals[*hour] += count
+		}
+		matrix.Total += count
+	}
+	return matrix, nil
+}
+
+// 7x24 weekday by hour counts. Takes the usual crime filters.
+func (h *Handler) GetHourMatrix(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	matrix, err := h.getHourMatrix(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build hour matrix",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matrix":       matrix,
+		"year":         filters.Years,
+		"crime_type":   filters.CrimeTypes,
+		"neighborhood": filters.Neighborhoods,
+		"start_date":   filters.StartDate,
+		"end_date":     filters.EndDate,
+	})
+}
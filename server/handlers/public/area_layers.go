@@ -0,0 +1,97 @@
+package public
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Neighborhoods come from the addresses table, every other layer from the
+// admin_areas boundaries loaded by the data parser
+const NEIGHBORHOOD_LAYER = "neighborhood"
+
+type AreaLayer struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Areas       int    `json:"areas"`
+}
+
+// Joins off addresses a and the area name column for grouping incidents by a
+// layer. placeholder is the parameter number holding the layer name, it is
+// not used for neighborhoods.
+func areaLayerSQL(layer string, placeholder int) (join, name string) {
+	if layer == NEIGHBORHOOD_LAYER {
+		return `
+			LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id`,
+			"COALESCE(n.neighborhood_name, 'Unknown Area')"
+	}
+	return fmt.Sprintf(`
+			LEFT JOIN address_areas aa ON aa.address_id = a.address_id
+				AND aa.layer_id = (SELECT layer_id FROM area_layers WHERE layer_name = $%d)
+			LEFT JOIN admin_areas ar ON aa.area_id = ar.area_id`, placeholder),
+		"COALESCE(ar.area_name, 'Unknown Area')"
+}
+
+// Reads the layer query param, writing the error response when it is unknown
+func (h *Handler) layerParam(c *gin.Context) (string, bool) {
+	layer := c.DefaultQuery("layer", NEIGHBORHOOD_LAYER)
+	if layer == NEIGHBORHOOD_LAYER {
+		return layer, true
+	}
+
+	var exists bool
+	err := h.pool.QueryRow(context.Background(),
+		"SELECT EXISTS (SELECT 1 FROM area_layers WHERE layer_name = $1)", layer).Scan(&exists)
+	if err != nil {
+		log.Printf("Error looking up area layer %s: %v", layer, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to look up area layer",
+		})
+		return layer, false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown layer %s", layer)})
+		return layer, false
+	}
+	return layer, true
+}
+
+// Layers the stats and areas endpoints can group by
+func (h *Handler) GetAreaLayers(c *gin.Context) {
+	layers := []AreaLayer{{Name: NEIGHBORHOOD_LAYER, Description: "Neighborhoods from geocoded addresses"}}
+	if err := h.pool.QueryRow(context.Background(),
+		"SELECT COUNT(*) FROM neighborhoods").Scan(&layers[0].Areas); err != nil {
+		log.Printf("Error counting neighborhoods: %v", err)
+	}
+
+	query := `
+		SELECT l.layer_name, COALESCE(l.description, ''), COUNT(ar.area_id)
+		FROM area_layers l
+		LEFT JOIN admin_areas ar ON ar.layer_id = l.layer_id
+		GROUP BY l.layer_id, l.layer_name, l.description
+		ORDER BY l.layer_name
+	`
+	rows, err := h.pool.Query(context.Background(), query)
+	if err != nil {
+		log.Printf("Error querying area layers: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve area layers",
+		})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var layer AreaLayer
+		if err := rows.Scan(&layer.Name, &layer.Description, &layer.Areas); err != nil {
+			log.Printf("Error scanning area layer: %v", err)
+			continue
+		}
+		layers = append(layers, layer)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"layers": layers})
+}
@@ -0,0 +1,344 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const CAMPUS_MAX_BUFFER_M = 5000
+
+type CampusEntrance struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type Campus struct {
+	Name      string           `json:"name"`
+	Slug      string           `json:"slug"`
+	City      string           `json:"city"`
+	Boundary  json.RawMessage  `json:"boundary"`
+	Entrances []CampusEntrance `json:"entrances"`
+	id        int64
+}
+
+type CrimeNearCampus struct {
+	CrimeDump
+	Distance float64 `json:"distance_m"`
+	OnCampus bool    `json:"on_campus"`
+}
+
+type CampusRing struct {
+	From       float64       `json:"from_m"`
+	To         float64       `json:"to_m"`
+	Count      int           `json:"count"`
+	Categories []OrderedPair `json:"categories"`
+}
+
+// Distance in meters from an incident to the campus boundary or its closest
+// entrance, with $1 the campus id. Zero inside the boundary.
+var campusDistances = map[string]string{
+	"boundary":  "ST_Distance((SELECT boundary FROM campuses WHERE campus_id = $1), l.geog)",
+	"entrances": "(SELECT MIN(ST_Distance(e.geog, l.geog)) FROM campus_entrances e WHERE e.campus_id = $1)",
+}
+
+// Index friendly versions of distance <= $2
+var campusWithin = map[string]string{
+	"boundary":  "ST_DWithin((SELECT boundary FROM campuses WHERE campus_id = $1), l.geog, $2)",
+	"entrances": "EXISTS (SELECT 1 FROM campus_entrances e WHERE e.campus_id = $1 AND ST_DWithin(e.geog, l.geog, $2))",
+}
+
+const campusOnCampus = "ST_Covers((SELECT boundary FROM campuses WHERE campus_id = $1), l.geog)"
+
+func (h *Handler) getCampuses(slug string) ([]Campus, error) {
+	query := `
+		SELECT
+			cp.campus_id,
+			cp.campus_name,
+			cp.slug,
+			COALESCE(c.city_name, ''),
+			ST_AsGeoJSON(cp.boundary)
+		FROM campuses cp
+		LEFT JOIN cities c ON cp.city_id = c.city_id
+		WHERE $1 = '' OR cp.slug = $1
+		ORDER BY cp.campus_name
+	`
+	rows, err := h.pool.Query(context.Background(), query, slug)
+	if err != nil {
+		log.Printf("Error querying campuses: %v", err)
+		return nil, err
+	}
+
+	campuses := []Campus{}
+	for rows.Next() {
+		var campus Campus
+		var boundary string
+		if err := rows.Scan(&campus.id, &campus.Name, &campus.Slug, &campus.City, &boundary); err != nil {
+			log.Printf("Error scanning campus: %v", err)
+			continue
+		}
+		campus.Boundary = json.RawMessage(boundary)
+		campus.Entrances = []CampusEntrance{}
+		campuses = append(campuses, campus)
+	}
+	rows.Close()
+
+	ids := make([]int64, len(campuses))
+	byID := make(map[int64]int, len(campuses))
+	for i, campus := range campuses {
+		ids[i] = campus.id
+		byID[campus.id] = i
+	}
+
+	entrances, err := h.pool.Query(context.Background(), `
+		SELECT campus_id, entrance_name, ST_Y(geog::geometry), ST_X(geog::geometry)
+		FROM campus_entrances
+		WHERE campus_id = ANY($1)
+		ORDER BY campus_id, entrance_name
+	`, ids)
+	if err != nil {
+		log.Printf("Error querying campus entrances: %v", err)
+		return nil, err
+	}
+	defer entrances.Close()
+
+	for entrances.Next() {
+		var campusID int64
+		var e CampusEntrance
+		if err := entrances.Scan(&campusID, &e.Name, &e.Latitude, &e.Longitude); err != nil {
+			log.Printf("Error scanning entrance: %v", err)
+			continue
+		}
+		if i, ok := byID[campusID]; ok {
+			campuses[i].Entrances = append(campuses[i].Entrances, e)
+		}
+	}
+	return campuses, nil
+}
+
+// Looks up the :campus path param, writing the error response when it fails
+func (h *Handler) campusParam(c *gin.Context) (Campus, bool) {
+	campuses, err := h.getCampuses(c.Param("campus"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve campus",
+		})
+		return Campus{}, false
+	}
+	if len(campuses) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campus not found"})
+		return Campus{}, false
+	}
+	return campuses[0], true
+}
+
+func parseCampusFrom(c *gin.Context) (string, error) {
+	from := c.DefaultQuery("from", "boundary")
+	if _, ok := campusDistances[from]; !ok {
+		return from, fmt.Errorf("from must be boundary or entrances")
+	}
+	return from, nil
+}
+
+// Registered campuses with their boundaries and entrances
+func (h *Handler) GetCampuses(c *gin.Context) {
+	campuses, err := h.getCampuses("")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve campuses",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campuses": campuses,
+		"count":    len(campuses),
+	})
+}
+
+// Incidents on campus, or within buffer meters of it with buffer set.
+// from=entrances measures the buffer from the nearest entrance instead of the
+// boundary. Takes the usual crime filters.
+func (h *Handler) GetCampusCrimes(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := parseCampusFrom(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	buffer, err := strconv.ParseFloat(c.DefaultQuery("buffer", "0"), 64)
+	if err != nil || buffer < 0 || buffer > CAMPUS_MAX_BUFFER_M {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("buffer must be between 0 and %d meters", CAMPUS_MAX_BUFFER_M)})
+		return
+	}
+
+	limit := parseLimit(c.Query("limit"), 500, 5000)
+
+	campus, ok := h.campusParam(c)
+	if !ok {
+		return
+	}
+
+	query := "SELECT" + crimeDumpColumns + `,
+			` + campusDistances[from] + ` as distance_m,
+			` + campusOnCampus + ` as on_campus
+		` + crimeDumpFrom
+	args := []any{campus.id}
+	if buffer == 0 && from == "boundary" {
+		query += " AND " + campusOnCampus
+	} else {
+		args = append(args, buffer)
+		query += " AND " + campusWithin[from]
+	}
+	query, args = filters.appendWhere(query, args)
+	query += " ORDER BY distance_m, ci.incident_date DESC"
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying crimes near campus %s: %v", campus.Slug, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve campus crimes",
+		})
+		return
+	}
+	defer rows.Close()
+
+	crimes := []CrimeNearCampus{}
+	onCampus := 0
+	for rows.Next() {
+		var crime CrimeNearCampus
+		crime.CrimeDump, err = scanCrimeDump(rows, &crime.Distance, &crime.OnCampus)
+		if err != nil {
+			log.Printf("Error scanning campus crime: %v", err)
+			continue
+		}
+		if crime.OnCampus {
+			onCampus++
+		}
+		crimes = append(crimes, crime)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campus":     campus,
+		"crimes":     crimes,
+		"count":      len(crimes),
+		"on_campus":  onCampus,
+		"buffer_m":   buffer,
+		"from":       from,
+		"year":       filters.Years,
+		"crime_type": filters.CrimeTypes,
+	})
+}
+
+// Incident counts by distance ring around a campus. rings is a comma list of
+// outer edges in meters, the first ring also includes everything on campus.
+func (h *Handler) GetCampusRings(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := parseCampusFrom(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	edges := []float64{}
+	for _, s := range splitParam(c.DefaultQuery("rings", "250,500,1000,1600")) {
+		edge, err := strconv.ParseFloat(s, 64)
+		if err != nil || edge <= 0 || edge > CAMPUS_MAX_BUFFER_M {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rings must be distances between 0 and %d meters", CAMPUS_MAX_BUFFER_M)})
+			return
+		}
+		edges = append(edges, edge)
+	}
+	sort.Float64s(edges)
+	if len(edges) == 0 || len(edges) > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "between 1 and 20 rings are allowed"})
+		return
+	}
+
+	campus, ok := h.campusParam(c)
+	if !ok {
+		return
+	}
+
+	query := `
+		SELECT
+			` + campusDistances[from] + ` as distance_m,
+			COALESCE(cc.category_name, 'Other') as category_name,
+			COUNT(*) as count
+		` + crimeDumpFrom + `
+		AND ` + campusWithin[from]
+	args := []any{campus.id, edges[len(edges)-1]}
+	query, args = filters.appendWhere(query, args)
+	query += " GROUP BY l.location_id, l.geog, cc.category_name"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying rings for campus %s: %v", campus.Slug, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count campus rings",
+		})
+		return
+	}
+	defer rows.Close()
+
+	categories := make([]map[string]int, len(edges))
+	for i := range categories {
+		categories[i] = make(map[string]int)
+	}
+	for rows.Next() {
+		var distance float64
+		var category string
+		var count int
+		if err := rows.Scan(&distance, &category, &count); err != nil {
+			log.Printf("Error scanning campus ring: %v", err)
+			continue
+		}
+		ring := sort.SearchFloat64s(edges, distance)
+		if ring < len(edges) {
+			categories[ring][category] += count
+		}
+	}
+
+	rings := make([]CampusRing, len(edges))
+	total := 0
+	for i, edge := range edges {
+		rings[i] = CampusRing{To: edge, Categories: sortedPairs(categories[i])}
+		if i > 0 {
+			rings[i].From = edges[i-1]
+		}
+		for _, pair := range rings[i].Categories {
+			rings[i].Count += pair.Value
+		}
+		total += rings[i].Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campus":     campus.Name,
+		"slug":       campus.Slug,
+		"rings":      rings,
+		"from":       from,
+		"total":      total,
+		"year":       filters.Years,
+		"crime_type": filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,123 @@
+package public
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClearanceRate struct {
+	Key      string  `json:"key"`
+	Total    int     `json:"total"`
+	Resolved int     `json:"resolved"`
+	Rate     float64 `json:"rate"`
+}
+
+type ClearanceStats struct {
+	Overall       ClearanceRate   `json:"overall"`
+	Categories    []ClearanceRate `json:"categories"`
+	Neighborhoods []ClearanceRate `json:"neighborhoods"`
+	Months        []ClearanceRate `json:"months"`
+}
+
+type clearanceCounts map[string]*ClearanceRate
+
+func (cc clearanceCounts) add(key string, total, resolved int) {
+	if _, ok := cc[key]; !ok {
+		cc[key] = &ClearanceRate{Key: key}
+	}
+	cc[key].Total += total
+	cc[key].Resolved += resolved
+}
+
+// Rates filled in, ordered by less
+func (cc clearanceCounts) sorted(less func(a, b ClearanceRate) bool) []ClearanceRate {
+	rates := make([]ClearanceRate, 0, len(cc))
+	for _, rate := range cc {
+		rate.Rate = float64(rate.Resolved) / float64(rate.Total)
+		rates = append(rates, *rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return less(rates[i], rates[j]) })
+	return rates
+}
+
+func byTotal(a, b ClearanceRate) bool {
+	if a.Total != b.Total {
+		return a.Total > b.Total
+	}
+	return a.Key < b.Key
+}
+
+func (h *Handler) getClearanceStats(filters CrimeFilters) (ClearanceStats, error) {
+	query := `
+		SELECT
+			COALESCE(cc.category_name, 'Other') as category_name,
+			COALESCE(n.neighborhood_name, 'Unknown neighborhood') as neighborhood,
+			to_char(ci.incident_date, 'YYYY-MM') as month,
+			COUNT(*) as total,
+			COUNT(*) FILTER (WHERE ci.is_resolved) as resolved
+		` + crimeDumpFrom
+	query, args := filters.appendWhere(query, []any{})
+	query += " GROUP BY cc.category_name, n.neighborhood_name, to_char(ci.incident_date, 'YYYY-MM')"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying clearance stats: %v", err)
+		return ClearanceStats{}, err
+	}
+	defer rows.Close()
+
+	categories := make(clearanceCounts)
+	neighborhoods := make(clearanceCounts)
+	months := make(clearanceCounts)
+	var stats ClearanceStats
+	for rows.Next() {
+		var category, neighborhood, month string
+		var total, resolved int
+		if err := rows.Scan(&category, &neighborhood, &month, &total, &resolved); err != nil {
+			log.Printf("Error scanning clearance stats: %v", err)
+			continue
+		}
+		categories.add(category, total, resolved)
+		neighborhoods.add(neighborhood, total, resolved)
+		months.add(month, total, resolved)
+		stats.Overall.Total += total
+		stats.Overall.Resolved += resolved
+	}
+
+	stats.Overall.Key = "all"
+	if stats.Overall.Total > 0 {
+		stats.Overall.Rate = float64(stats.Overall.Resolved) / float64(stats.Overall.Total)
+	}
+	stats.Categories = categories.sorted(byTotal)
+	stats.Neighborhoods = neighborhoods.sorted(byTotal)
+	stats.Months = months.sorted(func(a, b ClearanceRate) bool { return a.Key < b.Key })
+	return stats, nil
+}
+
+// Share of incidents marked resolved (cleared, closed, arrest) overall and by
+// category, neighborhood and month. Takes the usual crime filters.
+func (h *Handler) GetClearanceStats(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.getClearanceStats(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to calculate clearance rates",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clearance":  stats,
+		"year":       filters.Years,
+		"crime_type": filters.CrimeTypes,
+	})
+}
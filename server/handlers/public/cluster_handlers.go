@@ -0,0 +1,296 @@
+package public
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const CLUSTER_MAX_INCIDENTS = 50000
+
+// One incident with its position in a local projection
+type incidentPoint struct {
+	x, y      float64
+	latitude  float64
+	longitude float64
+	category  string
+	date      string
+}
+
+type CrimeCluster struct {
+	ID               int           `json:"id"`
+	Count            int           `json:"count"`
+	CentroidLat      float64       `json:"centroid_latitude"`
+	CentroidLng      float64       `json:"centroid_longitude"`
+	DominantCategory string        `json:"dominant_category"`
+	Categories       []OrderedPair `json:"categories"`
+	FirstDate        string        `json:"first_date"`
+	LastDate         string        `json:"last_date"`
+	Hull             []Position    `json:"hull"`
+}
+
+// Reads up to limit+1 incidents so callers can tell when the cap was hit
+func (h *Handler) getIncidentPoints(filters CrimeFilters, limit int) ([]incidentPoint, error) {
+	query := `
+		SELECT
+			l.latitude::float8,
+			l.longitude::float8,
+			COALESCE(cc.category_name, 'Other') as category_name,
+			ci.incident_date::text
+		` + crimeDumpFrom
+	query, args := filters.appendWhere(query, []any{})
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying incident points: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []incidentPoint
+	for rows.Next() {
+		var p incidentPoint
+		if err := rows.Scan(&p.latitude, &p.longitude, &p.category, &p.date); err != nil {
+			log.Printf("Error scanning incident point: %v", err)
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// Fills in x and y around the mean position of the points
+func projectPoints(points []incidentPoint) localProjection {
+	if len(points) == 0 {
+		return newLocalProjection(47.2529, -122.4443)
+	}
+	var lat0, lng0 float64
+	for _, p := range points {
+		lat0 += p.latitude
+		lng0 += p.longitude
+	}
+	proj := newLocalProjection(lat0/float64(len(points)), lng0/float64(len(points)))
+	for i := range points {
+		points[i].x, points[i].y = proj.toXY(points[i].latitude, points[i].longitude)
+	}
+	return proj
+}
+
+// Labels each point with a cluster id, -1 for noise. Neighbor search uses a
+// grid of eps sized buckets so each lookup only checks the 9 around it.
+func dbscan(points []incidentPoint, eps float64, minPts int) []int {
+	type bucket struct{ x, y int }
+	grid := make(map[bucket][]int)
+	for i, p := range points {
+		b := bucket{int(math.Floor(p.x / eps)), int(math.Floor(p.y / eps))}
+		grid[b] = append(grid[b], i)
+	}
+
+	regionQuery := func(i int) []int {
+		p := points[i]
+		bx, by := int(math.Floor(p.x/eps)), int(math.Floor(p.y/eps))
+		var result []int
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[bucket{bx + dx, by + dy}] {
+					if math.Hypot(points[j].x-p.x, points[j].y-p.y) <= eps {
+						result = append(result, j)
+					}
+				}
+			}
+		}
+		return result
+	}
+
+	const unvisited, noise = -2, -1
+	labels := make([]int, len(points))
+	for i := range labels {
+		labels[i] = unvisited
+	}
+
+	// every point is queued at most once, so the seed lists stay O(n) even
+	// when eps covers most of the points
+	enqueued := make([]bool, len(points))
+	enqueue := func(seeds, nbrs []int) []int {
+		for _, j := range nbrs {
+			if !enqueued[j] && (labels[j] == unvisited || labels[j] == noise) {
+				enqueued[j] = true
+				seeds = append(seeds, j)
+			}
+		}
+		return seeds
+	}
+
+	cluster := 0
+	for i := range points {
+		if labels[i] != unvisited {
+			continue
+		}
+		nbrs := regionQuery(i)
+		if len(nbrs) < minPts {
+			labels[i] = noise
+			continue
+		}
+
+		labels[i] = cluster
+		enqueued[i] = true
+		seeds := enqueue(nil, nbrs)
+		for k := 0; k < len(seeds); k++ {
+			j := seeds[k]
+			if labels[j] == noise {
+				labels[j] = cluster // border point
+				continue
+			}
+			labels[j] = cluster
+			if nbrs := regionQuery(j); len(nbrs) >= minPts {
+				seeds = enqueue(seeds, nbrs)
+			}
+		}
+		cluster++
+	}
+	return labels
+}
+
+// Andrew's monotone chain, returns a closed counter clockwise ring
+func convexHull(points []Position) []Position {
+	pts := append([]Position(nil), points...)
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i][0] != pts[j][0] {
+			return pts[i][0] < pts[j][0]
+		}
+		return pts[i][1] < pts[j][1]
+	})
+
+	unique := pts[:0]
+	for i, p := range pts {
+		if i == 0 || p != pts[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	pts = unique
+	if len(pts) < 3 {
+		return append(pts, pts[0])
+	}
+
+	cross := func(o, a, b Position) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+
+	hull := make([]Position, 0, 2*len(pts))
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], pts[i]) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, pts[i])
+	}
+	return hull
+}
+
+// Density based clusters (DBSCAN) of the incidents matching the filters.
+// eps is in meters, min_pts is the core point threshold.
+func (h *Handler) GetCrimeClusters(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	eps, err := strconv.ParseFloat(c.DefaultQuery("eps", "75"), 64)
+	if err != nil || eps < 5 || eps > 2000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "eps must be between 5 and 2000 meters"})
+		return
+	}
+
+	minPts, err := strconv.Atoi(c.DefaultQuery("minPts", "10"))
+	if err != nil || minPts < 2 || minPts > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minPts must be between 2 and 1000"})
+		return
+	}
+
+	points, err := h.getIncidentPoints(filters, CLUSTER_MAX_INCIDENTS)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to cluster crimes",
+		})
+		return
+	}
+	if len(points) > CLUSTER_MAX_INCIDENTS {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("more than %d incidents match, narrow the filters", CLUSTER_MAX_INCIDENTS),
+		})
+		return
+	}
+
+	proj := projectPoints(points)
+
+	labels := dbscan(points, eps, minPts)
+
+	members := make(map[int][]int)
+	noise := 0
+	for i, label := range labels {
+		if label < 0 {
+			noise++
+			continue
+		}
+		members[label] = append(members[label], i)
+	}
+
+	clusters := make([]CrimeCluster, 0, len(members))
+	for _, idx := range members {
+		cluster := CrimeCluster{Count: len(idx)}
+		categories := make(map[string]int)
+		hullPoints := make([]Position, 0, len(idx))
+		var sumX, sumY float64
+		for _, i := range idx {
+			p := points[i]
+			sumX += p.x
+			sumY += p.y
+			categories[p.category]++
+			hullPoints = append(hullPoints, Position{p.longitude, p.latitude})
+			if cluster.FirstDate == "" || p.date < cluster.FirstDate {
+				cluster.FirstDate = p.date
+			}
+			if p.date > cluster.LastDate {
+				cluster.LastDate = p.date
+			}
+		}
+		cluster.CentroidLat, cluster.CentroidLng = proj.toLatLng(sumX/float64(len(idx)), sumY/float64(len(idx)))
+		cluster.Categories = sortedPairs(categories)
+		cluster.DominantCategory = cluster.Categories[0].Key
+		cluster.Hull = convexHull(hullPoints)
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Count > clusters[j].Count
+	})
+	for i := range clusters {
+		clusters[i].ID = i + 1
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters":        clusters,
+		"total_clusters":  len(clusters),
+		"total_incidents": len(points),
+		"noise":           noise,
+		"eps_m":           eps,
+		"min_pts":         minPts,
+		"year":            filters.Years,
+		"crime_type":      filters.CrimeTypes,
+	})
+}
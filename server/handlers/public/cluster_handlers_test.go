@@ -0,0 +1,74 @@
+package public
+
+import "testing"
+
+// n points on a small square grid around (cx, cy), spacing meters apart
+func blob(cx, cy, spacing float64, n int) []incidentPoint {
+	points := make([]incidentPoint, 0, n)
+	for i := 0; i < n; i++ {
+		points = append(points, incidentPoint{
+			x: cx + float64(i%4)*spacing,
+			y: cy + float64(i/4)*spacing,
+		})
+	}
+	return points
+}
+
+func TestDBSCAN(t *testing.T) {
+	twoBlobs := append(blob(0, 0, 10, 12), blob(1000, 1000, 10, 12)...)
+	withNoise := append(append([]incidentPoint{}, twoBlobs...),
+		incidentPoint{x: 500, y: 500},
+		incidentPoint{x: -800, y: 300},
+	)
+
+	tests := []struct {
+		name     string
+		points   []incidentPoint
+		eps      float64
+		minPts   int
+		clusters int
+		noise    int
+	}{
+		{"empty", nil, 50, 3, 0, 0},
+		{"two blobs", twoBlobs, 50, 3, 2, 0},
+		{"two blobs plus noise", withNoise, 50, 3, 2, 2},
+		{"minPts above blob size", withNoise, 50, 13, 0, len(withNoise)},
+		{"eps joins everything", withNoise, 5000, 3, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels := dbscan(tt.points, tt.eps, tt.minPts)
+			if len(labels) != len(tt.points) {
+				t.Fatalf("got %d labels for %d points", len(labels), len(tt.points))
+			}
+
+			clusters := make(map[int]bool)
+			noise := 0
+			for _, label := range labels {
+				if label == -1 {
+					noise++
+				} else {
+					clusters[label] = true
+				}
+			}
+			if len(clusters) != tt.clusters || noise != tt.noise {
+				t.Errorf("got %d clusters and %d noise, want %d and %d", len(clusters), noise, tt.clusters, tt.noise)
+			}
+
+			// each blob lands in a single cluster
+			if tt.clusters == 2 {
+				for _, blob := range [][2]int{{0, 12}, {12, 24}} {
+					for i := blob[0]; i < blob[1]; i++ {
+						if labels[i] != labels[blob[0]] {
+							t.Errorf("point %d labelled %d, want %d", i, labels[i], labels[blob[0]])
+						}
+					}
+				}
+				if labels[0] == labels[12] {
+					t.Errorf("both blobs labelled %d", labels[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package public
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Counts for one category or neighborhood in the base and compare ranges.
+// PercentChange is on the daily rate so ranges of different length compare
+// fairly, it matches the change in counts when the ranges are the same length.
+type PeriodComparison struct {
+	Name          string   `json:"name"`
+	BaseCount     int      `json:"base_count"`
+	CompareCount  int      `json:"compare_count"`
+	Change        int      `json:"change"`
+	PercentChange *float64 `json:"percent_change"`
+	RateRatio     *float64 `json:"rate_ratio"`
+	PValue        float64  `json:"p_value"`
+	Significant   bool     `json:"significant"`
+}
+
+type dateRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+func parseDateRange(c *gin.Context, startParam, endParam string) (dateRange, error) {
+	r := dateRange{Start: c.Query(startParam), End: c.Query(endParam)}
+	start, errStart := time.Parse(time.DateOnly, r.Start)
+	end, errEnd := time.Parse(time.DateOnly, r.End)
+	if errStart != nil || errEnd != nil {
+		return r, fmt.Errorf("%s and %s are required as YYYY-MM-DD", startParam, endParam)
+	}
+	if end.Before(start) {
+		return r, fmt.Errorf("%s must not be before %s", endParam, startParam)
+	}
+	r.Days = int(end.Sub(start).Hours()/24) + 1
+	return r, nil
+}
+
+func comparePeriods(name string, base, compare int, baseDays, compareDays float64) PeriodComparison {
+	row := PeriodComparison{
+		Name:         name,
+		BaseCount:    base,
+		CompareCount: compare,
+		Change:       compare - base,
+		PValue:       poissonRateTest(base, baseDays, compare, compareDays),
+	}
+	if base > 0 {
+		ratio := (float64(compare) / compareDays) / (float64(base) / baseDays)
+		percent := (ratio - 1) * 100
+		row.RateRatio = &ratio
+		row.PercentChange = &percent
+	}
+	row.Significant = row.PValue < 0.05
+	return row
+}
+
+// Sorted by the size of the change, largest increases first
+func comparisonRows(base, compare map[string]int, baseDays, compareDays float64) []PeriodComparison {
+	names := make(map[string]bool)
+	for name := range base {
+		names[name] = true
+	}
+	for name := range compare {
+		names[name] = true
+	}
+
+	rows := make([]PeriodComparison, 0, len(names))
+	for name := range names {
+		rows = append(rows, comparePeriods(name, base[name], compare[name], baseDays, compareDays))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Change != rows[j].Change {
+			return rows[i].Change > rows[j].Change
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+// Compares two date ranges, e.g. this year to date against the same days last
+// year. baseStart/baseEnd and compareStart/compareEnd are required, the other
+// crime filters apply to both ranges.
+func (h *Handler) GetCrimeComparison(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	base, err := parseDateRange(c, "baseStart", "baseEnd")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	compare, err := parseDateRange(c, "compareStart", "compareEnd")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the ranges take the place of the year and date filters
+	filters.Years = nil
+	filters.StartDate = ""
+	filters.EndDate = ""
+
+	query := `
+		SELECT
+			COALESCE(cc.category_name, 'Other') as category_name,
+			COALESCE(n.neighborhood_name, 'Unknown neighborhood') as neighborhood,
+			COUNT(*) FILTER (WHERE ci.incident_date BETWEEN $1 AND $2) as base_count,
+			COUNT(*) FILTER (WHERE ci.incident_date BETWEEN $3 AND $4) as compare_count
+		` + crimeDumpFrom + `
+		AND (ci.incident_date BETWEEN $1 AND $2 OR ci.incident_date BETWEEN $3 AND $4)
+	`
+	query, args := filters.appendWhere(query, []any{base.Start, base.End, compare.Start, compare.End})
+	query += " GROUP BY category_name, neighborhood"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying period comparison: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to compare periods",
+		})
+		return
+	}
+	defer rows.Close()
+
+	baseByCategory := make(map[string]int)
+	compareByCategory := make(map[string]int)
+	baseByNeighborhood := make(map[string]int)
+	compareByNeighborhood := make(map[string]int)
+	baseTotal, compareTotal := 0, 0
+
+	for rows.Next() {
+		var category, neighborhood string
+		var baseCount, compareCount int
+		if err := rows.Scan(&category, &neighborhood, &baseCount, &compareCount); err != nil {
+			log.Printf("Error scanning period comparison: %v", err)
+			continue
+		}
+		baseByCategory[category] += baseCount
+		compareByCategory[category] += compareCount
+		baseByNeighborhood[neighborhood] += baseCount
+		compareByNeighborhood[neighborhood] += compareCount
+		baseTotal += baseCount
+		compareTotal += compareCount
+	}
+
+	baseDays, compareDays := float64(base.Days), float64(compare.Days)
+	c.JSON(http.StatusOK, gin.H{
+		"base":            base,
+		"compare":         compare,
+		"total":           comparePeriods("total", baseTotal, compareTotal, baseDays, compareDays),
+		"by_category":     comparisonRows(baseByCategory, compareByCategory, baseDays, compareDays),
+		"by_neighborhood": comparisonRows(baseByNeighborhood, compareByNeighborhood, baseDays, compareDays),
+		"crime_type":      filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,95 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const DISPLACEMENT_MAX_BUFFER_M = 2000
+
+// Weighted displacement quotient (Bowers and Johnson 2003). The buffer measure
+// is the change in the buffer relative to the control, the success measure the
+// same for the target. WDQ is their ratio and is only defined when the target
+// improved: above 0 is a diffusion of benefits into the buffer, below 0 is
+// displacement, and below -1 means more crime moved than was prevented.
+type DisplacementQuotient struct {
+	SuccessMeasure float64  `json:"success_measure"`
+	BufferMeasure  float64  `json:"buffer_measure"`
+	WDQ            *float64 `json:"wdq"`
+	Outcome        string   `json:"outcome"`
+}
+
+func weightedDisplacement(target, buffer, control RegionCounts) DisplacementQuotient {
+	var q DisplacementQuotient
+	if control.Pre == 0 || control.Post == 0 {
+		q.Outcome = "no control incidents"
+		return q
+	}
+	cPre, cPost := float64(control.Pre), float64(control.Post)
+	q.SuccessMeasure = float64(target.Post)/cPost - float64(target.Pre)/cPre
+	q.BufferMeasure = float64(buffer.Post)/cPost - float64(buffer.Pre)/cPre
+
+	if q.SuccessMeasure >= 0 {
+		q.Outcome = "no reduction in target"
+		return q
+	}
+	wdq := q.BufferMeasure / q.SuccessMeasure
+	q.WDQ = &wdq
+	switch {
+	case wdq > 0:
+		q.Outcome = "diffusion of benefits"
+	case wdq < -1:
+		q.Outcome = "displacement exceeds reduction"
+	case wdq < 0:
+		q.Outcome = "displacement"
+	default:
+		q.Outcome = "no change in buffer"
+	}
+	return q
+}
+
+// Displacement or diffusion of benefits around an intervention area. Takes
+// the target, optional control, date and days of /crimes/intervention plus
+// buffer, the width in meters of the catchment ring around the target. Counts
+// for each region and window are returned alongside the WDQ so it can be
+// reproduced.
+func (h *Handler) GetDisplacement(c *gin.Context) {
+	filters, iv, z, ok := interventionParams(c, "POST /api/public/crimes/displacement?date=2025-03-01&days=180&buffer=400 with {\"target\": <GeoJSON polygon>}")
+	if !ok {
+		return
+	}
+
+	buffer, err := strconv.ParseFloat(c.DefaultQuery("buffer", "400"), 64)
+	if err != nil || buffer <= 0 || buffer > DISPLACEMENT_MAX_BUFFER_M {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("buffer must be between 0 and %d meters", DISPLACEMENT_MAX_BUFFER_M)})
+		return
+	}
+
+	regions, err := h.getRegionCounts(filters, iv, buffer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to calculate displacement",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":            iv.date.Format(time.DateOnly),
+		"pre":             iv.pre,
+		"post":            iv.post,
+		"buffer_m":        buffer,
+		"target":          regions["target"],
+		"buffer":          regions["buffer"],
+		"control":         regions["control"],
+		"control_area":    iv.control != nil,
+		"quotient":        weightedDisplacement(regions["target"], regions["buffer"], regions["control"]),
+		"target_estimate": differenceInDifferences(regions["target"], regions["control"], z),
+		"buffer_estimate": differenceInDifferences(regions["buffer"], regions["control"], z),
+		"interval":        c.DefaultQuery("interval", "95"),
+		"crime_type":      filters.CrimeTypes,
+	})
+}
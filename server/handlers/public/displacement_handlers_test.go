@@ -0,0 +1,43 @@
+package public
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeightedDisplacement(t *testing.T) {
+	control := RegionCounts{100, 100}
+	wdq := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		target  RegionCounts
+		buffer  RegionCounts
+		control RegionCounts
+		wdq     *float64
+		outcome string
+	}{
+		{"buffer rose while target fell", RegionCounts{50, 30}, RegionCounts{40, 50}, control, wdq(-0.5), "displacement"},
+		{"buffer rose more than target fell", RegionCounts{50, 30}, RegionCounts{40, 70}, control, wdq(-1.5), "displacement exceeds reduction"},
+		{"buffer fell with target", RegionCounts{50, 30}, RegionCounts{40, 30}, control, wdq(0.5), "diffusion of benefits"},
+		{"buffer unchanged", RegionCounts{50, 30}, RegionCounts{40, 40}, control, wdq(0), "no change in buffer"},
+		{"target rose", RegionCounts{30, 50}, RegionCounts{40, 30}, control, nil, "no reduction in target"},
+		{"no control incidents", RegionCounts{50, 30}, RegionCounts{40, 50}, RegionCounts{10, 0}, nil, "no control incidents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := weightedDisplacement(tt.target, tt.buffer, tt.control)
+			if q.Outcome != tt.outcome {
+				t.Errorf("outcome %q, want %q", q.Outcome, tt.outcome)
+			}
+			switch {
+			case tt.wdq == nil && q.WDQ != nil:
+				t.Errorf("wdq %v, want none", *q.WDQ)
+			case tt.wdq != nil && q.WDQ == nil:
+				t.Errorf("no wdq, want %v", *tt.wdq)
+			case tt.wdq != nil && math.Abs(*q.WDQ-*tt.wdq) > 1e-9:
+				t.Errorf("wdq %v, want %v", *q.WDQ, *tt.wdq)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package public
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// Filters shared by the spatial and analytics endpoints.
+// Parameter names match /crimes/details so the client can reuse its filter state.
+type CrimeFilters struct {
+	Years         []string
+	CrimeTypes    []string
+	Cities        []string
+	Neighborhoods []string
+	Sources       []string
+	StartDate     string
+	EndDate       string
+	// exact matches set by handlers that resolved a city or neighborhood, not params
+	CityID         int64
+	NeighborhoodID int64
+}
+
+// Columns scanned by scanCrimeDump
+const crimeDumpColumns = `
+			COALESCE(ci.case_num, '') as case_num,
+			COALESCE(cc.category_name, 'Other') as category_name,
+			COALESCE(n.neighborhood_name, 'Unknown neighborhood') as neighborhood,
+			COALESCE(a.street_address, 'Unknown Address') as address,
+			c.city_name,
+			COALESCE(a.postal_code, '') as postal_code,
+			l.latitude as latitude,
+			l.longitude as longitude,
+			ci.incident_date::text,
+			COALESCE(ci.incident_time::text, '') as incident_time,
+			COALESCE(s.source_name, '') as source_name,
+			ci.is_resolved`
+
+const crimeDumpFrom = `
+		FROM crime_incidents_partition ci
+		JOIN addresses a ON ci.address_id = a.address_id
+		JOIN cities c ON a.city_id = c.city_id
+		JOIN locations l ON a.location_id = l.location_id
+		LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
+		LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
+		LEFT JOIN data_sources s ON ci.source_id = s.source_id
+		WHERE 1=1
+	`
+
+// Base query for CrimeDump rows read from the partitioned incident table
+const crimeDumpQuery = "SELECT" + crimeDumpColumns + crimeDumpFrom
+
+func splitParam(value string) []string {
+	values := []string{}
+	for v := range strings.SplitSeq(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func validateDate(date string) bool {
+	_, err := time.Parse(time.DateOnly, date)
+	return err == nil
+}
+
+func parseCrimeFilters(c *gin.Context) (CrimeFilters, error) {
+	f := CrimeFilters{
+		CrimeTypes:    splitParam(c.Query("crimeType")),
+		Cities:        splitParam(c.Query("city")),
+		Neighborhoods: splitParam(c.Query("neighborhood")),
+		Sources:       splitParam(c.Query("source")),
+		StartDate:     c.Query("startDate"),
+		EndDate:       c.Query("endDate"),
+	}
+
+	// radius and heat map endpoints call it type
+	if len(f.CrimeTypes) == 0 {
+		f.CrimeTypes = splitParam(c.Query("type"))
+	}
+
+	if f.StartDate != "" && !validateDate(f.StartDate) {
+		return f, fmt.Errorf("invalid startDate, expected YYYY-MM-DD")
+	}
+	if f.EndDate != "" && !validateDate(f.EndDate) {
+		return f, fmt.Errorf("invalid endDate, expected YYYY-MM-DD")
+	}
+
+	// an explicit date range replaces the default current year
+	year := c.Query("year")
+	if year != "" || (f.StartDate == "" && f.EndDate == "") {
+		f.Years = validateYears(year)
+		if len(f.Years) == 0 {
+			f.Years = []string{getCurrentYear()}
+		}
+	}
+
+	return f, nil
+}
+
+// Appends the filter conditions to query, numbering placeholders after args
+func (f CrimeFilters) appendWhere(query string, args []any) (string, []any) {
+	if len(f.Years) > 0 {
+		ranges := make([]string, len(f.Years))
+		for i, year := range f.Years {
+			y, _ := strconv.Atoi(year)
+			ranges[i] = fmt.Sprintf("(ci.incident_date >= '%d-01-01' AND ci.incident_date < '%d-01-01')", y, y+1)
+		}
+		query += " AND (" + strings.Join(ranges, " OR ") + ")"
+	}
+
+	if len(f.CrimeTypes) > 0 {
+		placeholders := make([]string, len(f.CrimeTypes))
+		for i, crimeType := range f.CrimeTypes {
+			args = append(args, strings.ToLower(crimeType))
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		query += fmt.Sprintf(" AND LOWER(cc.category_name) ILIKE ANY(ARRAY[%s])", strings.Join(placeholders, ","))
+	}
+
+	if len(f.Cities) > 0 {
+		placeholders := make([]string, len(f.Cities))
+		for i, city := range f.Cities {
+			args = append(args, "%"+city+"%")
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		query += fmt.Sprintf(" AND c.city_name ILIKE ANY(ARRAY[%s])", strings.Join(placeholders, ","))
+	}
+
+	if len(f.Neighborhoods) > 0 {
+		placeholders := make([]string, len(f.Neighborhoods))
+		for i, neighborhood := range f.Neighborhoods {
+			args = append(args, "%"+neighborhood+"%")
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		query += fmt.Sprintf(" AND n.neighborhood_name ILIKE ANY(ARRAY[%s])", strings.Join(placeholders, ","))
+	}
+
+	if f.CityID != 0 {
+		args = append(args, f.CityID)
+		query += fmt.Sprintf(" AND a.city_id = $%d", len(args))
+	}
+
+	if f.NeighborhoodID != 0 {
+		args = append(args, f.NeighborhoodID)
+		query += fmt.Sprintf(" AND a.neighborhood_id = $%d", len(args))
+	}
+
+	if len(f.Sources) > 0 {
+		placeholders := make([]string, len(f.Sources))
+		for i, source := range f.Sources {
+			args = append(args, source)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		query += fmt.Sprintf(" AND s.source_name IN (%s)", strings.Join(placeholders, ","))
+	}
+
+	if f.StartDate != "" {
+		args = append(args, f.StartDate)
+		query += fmt.Sprintf(" AND ci.incident_date >= $%d", len(args))
+	}
+	if f.EndDate != "" {
+		args = append(args, f.EndDate)
+		query += fmt.Sprintf(" AND ci.incident_date <= $%d", len(args))
+	}
+
+	return query, args
+}
+
+// z for the interval=80|95 confidence or prediction interval
+func parseInterval(c *gin.Context) (float64, error) {
+	switch c.DefaultQuery("interval", "95") {
+	case "80":
+		return 1.2816, nil
+	case "95":
+		return 1.96, nil
+	}
+	return 0, fmt.Errorf("interval must be 80 or 95")
+}
+
+func scanCrimeDump(rows pgx.Rows, extra ...any) (CrimeDump, error) {
+	var crime CrimeDump
+	dest := append([]any{
+		&crime.Case,
+		&crime.CrimeCategory,
+		&crime.Neighborhood,
+		&crime.Street,
+		&crime.City,
+		&crime.Zip,
+		&crime.Latitude,
+		&crime.Longitude,
+		&crime.Date,
+		&crime.Time,
+		&crime.Source,
+		&crime.Resolved,
+	}, extra...)
+	err := rows.Scan(dest...)
+	return crime, err
+}
+
+func parseLimit(limitStr string, def, max int) int {
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= max {
+			return l
+		}
+	}
+	return def
+}
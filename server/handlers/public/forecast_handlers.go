@@ -0,0 +1,302 @@
+package public
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Weeks in the annual cycle. ISO years with a week 53, about one in six,
+	// shift the lag a week for the year after, small next to week to week noise.
+	FORECAST_SEASON    = 52
+	FORECAST_MAX_WEEKS = 26
+)
+
+type ForecastPoint struct {
+	Week  string  `json:"week"`
+	Value float64 `json:"value"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+type ForecastSeries struct {
+	Name     string          `json:"name"`
+	Model    string          `json:"model"`
+	Weeks    int             `json:"history_weeks"`
+	RMSE     float64         `json:"rmse"`
+	Forecast []ForecastPoint `json:"forecast"`
+}
+
+// Point forecasts for the next horizon weeks and the standard error of each
+type forecastFit struct {
+	model  string
+	values []float64
+	stderr []float64
+	rmse   float64
+}
+
+// Additive Holt-Winters with one step ahead errors as the fit criterion.
+// season 0 drops the seasonal term, leaving Holt's linear trend method.
+func holtWinters(series []float64, season int, alpha, beta, gamma float64, horizon int) forecastFit {
+	level, trend := series[0], 0.0
+	seasonal := make([]float64, max(season, 1))
+	start := 1
+	if season > 0 {
+		first, second := 0.0, 0.0
+		for i := range season {
+			first += series[i]
+			second += series[season+i]
+		}
+		first /= float64(season)
+		second /= float64(season)
+		level, trend = first, (second-first)/float64(season)
+		for i := range season {
+			seasonal[i] = series[i] - first
+		}
+		start = season
+	} else if len(series) > 1 {
+		trend = series[1] - series[0]
+	}
+
+	sse, errs := 0.0, 0
+	for t := start; t < len(series); t++ {
+		s := 0.0
+		if season > 0 {
+			s = seasonal[t%season]
+		}
+		e := series[t] - (level + trend + s)
+		sse += e * e
+		errs++
+
+		prev := level
+		level = alpha*(series[t]-s) + (1-alpha)*(level+trend)
+		trend = beta*(level-prev) + (1-beta)*trend
+		if season > 0 {
+			seasonal[t%season] = gamma*(series[t]-level) + (1-gamma)*s
+		}
+	}
+
+	fit := forecastFit{
+		model:  "holt-winters",
+		values: make([]float64, horizon),
+		stderr: make([]float64, horizon),
+		rmse:   math.Sqrt(sse / float64(max(errs, 1))),
+	}
+	if season == 0 {
+		fit.model = "holt"
+	}
+
+	// variance of the h step error grows with the smoothing weights
+	variance := 0.0
+	for h := 1; h <= horizon; h++ {
+		s := 0.0
+		if season > 0 {
+			s = seasonal[(len(series)+h-1)%season]
+		}
+		fit.values[h-1] = level + float64(h)*trend + s
+		if h > 1 {
+			c := alpha * (1 + float64(h-1)*beta)
+			variance += c * c
+		}
+		fit.stderr[h-1] = fit.rmse * math.Sqrt(1+variance)
+	}
+	return fit
+}
+
+// Smoothing weights chosen from a small grid by one step ahead error
+func fitHoltWinters(series []float64, season, horizon int) forecastFit {
+	gammas := []float64{0}
+	if season > 0 {
+		gammas = []float64{0.05, 0.1, 0.2, 0.4}
+	}
+
+	var best forecastFit
+	for _, alpha := range []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7} {
+		for _, beta := range []float64{0, 0.01, 0.05, 0.1, 0.2} {
+			for _, gamma := range gammas {
+				fit := holtWinters(series, season, alpha, beta, gamma, horizon)
+				if best.values == nil || fit.rmse < best.rmse {
+					best = fit
+				}
+			}
+		}
+	}
+	return best
+}
+
+// Same week last year, or the last week when there is less than a year
+func seasonalNaive(series []float64, season, horizon int) forecastFit {
+	n := len(series)
+	fit := forecastFit{
+		model:  "seasonal-naive",
+		values: make([]float64, horizon),
+		stderr: make([]float64, horizon),
+	}
+	if n <= season {
+		fit.model = "naive"
+		season = 1
+	}
+
+	sse := 0.0
+	for t := season; t < n; t++ {
+		e := series[t] - series[t-season]
+		sse += e * e
+	}
+	fit.rmse = math.Sqrt(sse / float64(max(n-season, 1)))
+
+	for h := 1; h <= horizon; h++ {
+		fit.values[h-1] = series[n-season+(h-1)%season]
+		fit.stderr[h-1] = fit.rmse * math.Sqrt(float64((h-1)/season+1))
+	}
+	return fit
+}
+
+// Monday of an ISO week key such as 2024-W07
+func isoWeekStart(key string) (time.Time, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(key, "%d-W%d", &year, &week); err != nil {
+		return time.Time{}, err
+	}
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, 7*(week-1)), nil
+}
+
+func forecastSeries(name string, series []float64, method string, lastWeek time.Time, weeks int, z float64) ForecastSeries {
+	var fit forecastFit
+	switch {
+	case method == "seasonal-naive":
+		fit = seasonalNaive(series, FORECAST_SEASON, weeks)
+	case len(series) >= 2*FORECAST_SEASON:
+		fit = fitHoltWinters(series, FORECAST_SEASON, weeks)
+	case len(series) >= 8:
+		fit = fitHoltWinters(series, 0, weeks)
+	default:
+		fit = seasonalNaive(series, FORECAST_SEASON, weeks)
+	}
+
+	result := ForecastSeries{
+		Name:     name,
+		Model:    fit.model,
+		Weeks:    len(series),
+		RMSE:     fit.rmse,
+		Forecast: make([]ForecastPoint, weeks),
+	}
+	for h := range weeks {
+		value := math.Max(0, fit.values[h])
+		result.Forecast[h] = ForecastPoint{
+			Week:  trendPeriods["isoweek"].key(lastWeek.AddDate(0, 0, 7*(h+1))),
+			Value: value,
+			Lower: math.Max(0, fit.values[h]-z*fit.stderr[h]),
+			Upper: math.Max(0, fit.values[h]+z*fit.stderr[h]),
+		}
+	}
+	return result
+}
+
+// Weekly forecast for the next weeks with prediction intervals, per category
+// or neighborhood plus the overall total. method=holt-winters picks the annual
+// seasonal model when two years of history are available and falls back to
+// Holt's trend method, method=seasonal-naive repeats the same week last year.
+// Without a year or date range the last three years are used as history.
+func (h *Handler) GetCrimeForecast(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("year") == "" && filters.StartDate == "" {
+		filters.Years = nil
+		filters.StartDate = time.Now().UTC().AddDate(-3, 0, 0).Format(time.DateOnly)
+	}
+
+	weeks, err := strconv.Atoi(c.DefaultQuery("weeks", "8"))
+	if err != nil || weeks < 1 || weeks > FORECAST_MAX_WEEKS {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("weeks must be between 1 and %d", FORECAST_MAX_WEEKS)})
+		return
+	}
+
+	group := c.DefaultQuery("group", "category")
+	if _, ok := trendGroups[group]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group must be category or neighborhood"})
+		return
+	}
+
+	method := c.DefaultQuery("method", "holt-winters")
+	if method != "holt-winters" && method != "seasonal-naive" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method must be holt-winters or seasonal-naive"})
+		return
+	}
+
+	z, err := parseInterval(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := parseLimit(c.Query("limit"), 10, 50)
+
+	trends, err := h.calculateCrimeTrends(filters, trendPeriods["isoweek"], group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to forecast crimes",
+		})
+		return
+	}
+
+	// the current week is still filling up and the first may start mid week
+	buckets := trends.Buckets
+	if n := len(buckets); n > 0 && buckets[n-1].Period == trendPeriods["isoweek"].key(time.Now().UTC()) {
+		buckets = buckets[:n-1]
+	}
+	if len(buckets) > 0 && filters.StartDate != "" {
+		monday, err := isoWeekStart(buckets[0].Period)
+		if err == nil && monday.Format(time.DateOnly) < filters.StartDate {
+			buckets = buckets[1:]
+		}
+	}
+	if len(buckets) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "not enough weekly history to forecast"})
+		return
+	}
+
+	lastWeek, err := isoWeekStart(buckets[len(buckets)-1].Period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to forecast crimes",
+		})
+		return
+	}
+
+	total := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		total[i] = float64(bucket.Total)
+	}
+
+	series := []ForecastSeries{}
+	for g, name := range trends.Categories {
+		if g >= limit {
+			break
+		}
+		values := make([]float64, len(buckets))
+		for i, bucket := range buckets {
+			values[i] = float64(bucket.Counts[g].Value)
+		}
+		series = append(series, forecastSeries(name, values, method, lastWeek, weeks, z))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":      forecastSeries("total", total, method, lastWeek, weeks, z),
+		"series":     series,
+		"group":      group,
+		"method":     method,
+		"interval":   c.DefaultQuery("interval", "95"),
+		"weeks":      weeks,
+		"crime_type": filters.CrimeTypes,
+	})
+}
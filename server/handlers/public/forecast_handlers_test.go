@@ -0,0 +1,51 @@
+package public
+
+import (
+	"math"
+	"testing"
+)
+
+// n weeks of f(t)
+func weeklySeries(n int, f func(t int) float64) []float64 {
+	series := make([]float64, n)
+	for t := range series {
+		series[t] = f(t)
+	}
+	return series
+}
+
+func TestFitHoltWinters(t *testing.T) {
+	pattern := []float64{10, 20, 15, 5}
+	tests := []struct {
+		name     string
+		series   []float64
+		season   int
+		model    string
+		forecast []float64
+		tol      float64
+	}{
+		{"flat", weeklySeries(20, func(int) float64 { return 12 }), 0, "holt", []float64{12, 12, 12}, 1e-6},
+		{"linear trend", weeklySeries(20, func(t int) float64 { return 10 + 2*float64(t) }), 0, "holt", []float64{50, 52, 54}, 1e-6},
+		{"repeating season", weeklySeries(24, func(t int) float64 { return pattern[t%4] }), 4, "holt-winters", []float64{10, 20, 15, 5, 10}, 1e-6},
+		// the first season's indices start without the trend, so the fit only
+		// converges on it
+		{"season on a trend", weeklySeries(24, func(t int) float64 { return pattern[t%4] + float64(t) }), 4, "holt-winters", []float64{34, 45, 41, 32}, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit := fitHoltWinters(tt.series, tt.season, len(tt.forecast))
+			if fit.model != tt.model {
+				t.Errorf("model %q, want %q", fit.model, tt.model)
+			}
+			for h, want := range tt.forecast {
+				if math.Abs(fit.values[h]-want) > tt.tol {
+					t.Errorf("week %d forecast %v, want %v", h+1, fit.values[h], want)
+				}
+				if h > 0 && fit.stderr[h] < fit.stderr[h-1] {
+					t.Errorf("stderr shrinks from %v to %v", fit.stderr[h-1], fit.stderr[h])
+				}
+			}
+		})
+	}
+}
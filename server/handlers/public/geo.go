@@ -0,0 +1,240 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// [lng, lat] like GeoJSON
+type Position [2]float64
+
+// First ring is the exterior, the rest are holes
+type Polygon [][]Position
+
+type MultiPolygon []Polygon
+
+type BoundingBox struct {
+	MinLng float64 `json:"min_lng"`
+	MinLat float64 `json:"min_lat"`
+	MaxLng float64 `json:"max_lng"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+type geoJSONObject struct {
+	Type        string            `json:"type"`
+	Coordinates json.RawMessage   `json:"coordinates"`
+	Geometry    *geoJSONObject    `json:"geometry"`
+	Features    []json.RawMessage `json:"features"`
+}
+
+// Parses "minLng,minLat,maxLng,maxLat", the GeoJSON bbox order
+func parseBoundingBox(bbox string) (BoundingBox, error) {
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 4 {
+		return BoundingBox{}, fmt.Errorf("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+
+	var vals [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BoundingBox{}, fmt.Errorf("bbox must be minLng,minLat,maxLng,maxLat")
+		}
+		vals[i] = v
+	}
+
+	b := BoundingBox{MinLng: vals[0], MinLat: vals[1], MaxLng: vals[2], MaxLat: vals[3]}
+	if b.MinLng >= b.MaxLng || b.MinLat >= b.MaxLat {
+		return BoundingBox{}, fmt.Errorf("bbox minimums must be less than maximums")
+	}
+	if b.MinLat < -90 || b.MaxLat > 90 || b.MinLng < -180 || b.MaxLng > 180 {
+		return BoundingBox{}, fmt.Errorf("bbox is out of range")
+	}
+	return b, nil
+}
+
+// Accepts a Polygon, MultiPolygon, Feature or FeatureCollection of those
+func parseGeoJSONPolygon(data []byte) (MultiPolygon, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	var mp MultiPolygon
+	switch obj.Type {
+	case "Polygon":
+		var p Polygon
+		if err := json.Unmarshal(obj.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %w", err)
+		}
+		mp = MultiPolygon{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(obj.Coordinates, &mp); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %w", err)
+		}
+	case "Feature":
+		if obj.Geometry == nil {
+			return nil, fmt.Errorf("feature has no geometry")
+		}
+		geometry, err := json.Marshal(obj.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		return parseGeoJSONPolygon(geometry)
+	case "FeatureCollection":
+		for _, feature := range obj.Features {
+			fmp, err := parseGeoJSONPolygon(feature)
+			if err != nil {
+				return nil, err
+			}
+			mp = append(mp, fmp...)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported GeoJSON type %q, expected Polygon or MultiPolygon", obj.Type)
+	}
+
+	if len(mp) == 0 {
+		return nil, fmt.Errorf("GeoJSON contains no polygons")
+	}
+	for _, p := range mp {
+		if len(p) == 0 || len(p[0]) < 4 {
+			return nil, fmt.Errorf("polygon rings need at least 4 positions")
+		}
+		for _, ring := range p {
+			for _, pos := range ring {
+				if pos[1] < -90 || pos[1] > 90 || pos[0] < -180 || pos[0] > 180 {
+					return nil, fmt.Errorf("polygon coordinate out of range")
+				}
+			}
+		}
+	}
+	return mp, nil
+}
+
+func (mp MultiPolygon) Bounds() BoundingBox {
+	b := BoundingBox{MinLng: math.Inf(1), MinLat: math.Inf(1), MaxLng: math.Inf(-1), MaxLat: math.Inf(-1)}
+	for _, p := range mp {
+		for _, pos := range p[0] {
+			b.MinLng = math.Min(b.MinLng, pos[0])
+			b.MaxLng = math.Max(b.MaxLng, pos[0])
+			b.MinLat = math.Min(b.MinLat, pos[1])
+			b.MaxLat = math.Max(b.MaxLat, pos[1])
+		}
+	}
+	return b
+}
+
+func (mp MultiPolygon) GeoJSON() (string, error) {
+	data, err := json.Marshal(map[string]any{
+		"type":        "MultiPolygon",
+		"coordinates": mp,
+	})
+	return string(data), err
+}
+
+func lineStringGeoJSON(path []Position) (string, error) {
+	data, err := json.Marshal(map[string]any{
+		"type":        "LineString",
+		"coordinates": path,
+	})
+	return string(data), err
+}
+
+// Decodes a Google encoded polyline with 5 digits of precision
+func decodePolyline(encoded string) ([]Position, error) {
+	var path []Position
+	var lat, lng int
+	for i := 0; i < len(encoded); {
+		var deltas [2]int
+		for d := range deltas {
+			result, shift := 0, 0
+			for {
+				if i >= len(encoded) {
+					return nil, fmt.Errorf("polyline ends mid coordinate")
+				}
+				b := int(encoded[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, fmt.Errorf("invalid polyline character")
+				}
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[d] = ^(result >> 1)
+			} else {
+				deltas[d] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lng += deltas[1]
+		path = append(path, Position{float64(lng) / 1e5, float64(lat) / 1e5})
+	}
+	return path, nil
+}
+
+// Parses "lat,lng;lat,lng;..." waypoints
+func parseWaypoints(waypoints string) ([]Position, error) {
+	var path []Position
+	for _, point := range strings.Split(waypoints, ";") {
+		parts := strings.Split(point, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("waypoints must be lat,lng pairs separated by ;")
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("invalid waypoint latitude %q", parts[0])
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("invalid waypoint longitude %q", parts[1])
+		}
+		path = append(path, Position{lng, lat})
+	}
+	return path, nil
+}
+
+// Projects a point onto a path. Returns the distance along the path to the
+// closest point and the offset from it, both in miles.
+func projectOntoPath(path []Position, cumulative []float64, lng, lat float64) (along, offset float64) {
+	offset = math.Inf(1)
+	cosLat := math.Cos(lat * math.Pi / 180)
+
+	for i := 0; i < len(path)-1; i++ {
+		a, b := path[i], path[i+1]
+		// local equirectangular plane is plenty for segment lengths on a walk
+		ax, ay := a[0]*cosLat, a[1]
+		bx, by := b[0]*cosLat, b[1]
+		px, py := lng*cosLat, lat
+
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if segLen := dx*dx + dy*dy; segLen > 0 {
+			t = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/segLen))
+		}
+
+		qLng := a[0] + t*(b[0]-a[0])
+		qLat := a[1] + t*(b[1]-a[1])
+		d := haversineDistanceMiles(lat, lng, qLat, qLng)
+		if d < offset {
+			offset = d
+			along = cumulative[i] + haversineDistanceMiles(a[1], a[0], qLat, qLng)
+		}
+	}
+	return along, offset
+}
+
+// Running length of a path in miles, one entry per vertex
+func pathCumulativeMiles(path []Position) []float64 {
+	cumulative := make([]float64, len(path))
+	for i := 1; i < len(path); i++ {
+		cumulative[i] = cumulative[i-1] + haversineDistanceMiles(path[i-1][1], path[i-1][0], path[i][1], path[i][0])
+	}
+	return cumulative
+}
@@ -0,0 +1,39 @@
+package public
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		path    []Position
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"origin", "??", []Position{{0, 0}}, false},
+		{"google example", "_p~iF~ps|U_ulLnnqC_mqNvxq`@", []Position{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}}, false},
+		{"latitude without longitude", "_p~iF", nil, true},
+		{"cut mid value", "_p~iF~ps", nil, true},
+		{"invalid character", "_p~iF ~ps|U", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := decodePolyline(tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if len(path) != len(tt.path) {
+				t.Fatalf("got %d points, want %d", len(path), len(tt.path))
+			}
+			for i, p := range path {
+				if math.Abs(p[0]-tt.path[i][0]) > 1e-9 || math.Abs(p[1]-tt.path[i][1]) > 1e-9 {
+					t.Errorf("point %d is %v, want %v", i, p, tt.path[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package public
+
+import (
+	"fmt"
+	"math"
+)
+
+// Hierarchical hexagon grid in the spirit of H3. Cells are regular hexagons on
+// a Lambert azimuthal equal-area projection centered on the contiguous US, so
+// every cell at a resolution covers the same ground area. Each resolution is
+// aperture 7: edges shrink by sqrt(7) and the lattice turns by atan(sqrt(3)/5),
+// which puts every parent center on a child center like H3 does.
+
+const (
+	HEX_EARTH_RADIUS = 6371007.2 // authalic radius, meters
+	HEX_ORIGIN_LAT   = 39.5
+	HEX_ORIGIN_LNG   = -98.35
+	HEX_RES0_EDGE    = 1107712.591 // meters, H3 resolution 0 average edge
+	HEX_MIN_RES      = 0
+	HEX_MAX_RES      = 12
+)
+
+type HexCell struct {
+	Res int
+	Q   int
+	R   int
+}
+
+func hexEdgeMeters(res int) float64 {
+	return HEX_RES0_EDGE / math.Pow(math.Sqrt(7), float64(res))
+}
+
+func hexAreaKm2(res int) float64 {
+	e := hexEdgeMeters(res)
+	return 3 * math.Sqrt(3) / 2 * e * e / 1e6
+}
+
+func hexRotation(res int) float64 {
+	return float64(res) * math.Atan(math.Sqrt(3)/5)
+}
+
+// Lambert azimuthal equal-area, spherical form
+func laeaForward(lat, lng float64) (x, y float64) {
+	phi := lat * math.Pi / 180
+	lambda := lng * math.Pi / 180
+	phi0 := HEX_ORIGIN_LAT * math.Pi / 180
+	lambda0 := HEX_ORIGIN_LNG * math.Pi / 180
+
+	k := math.Sqrt(2 / (1 + math.Sin(phi0)*math.Sin(phi) + math.Cos(phi0)*math.Cos(phi)*math.Cos(lambda-lambda0)))
+	x = HEX_EARTH_RADIUS * k * math.Cos(phi) * math.Sin(lambda-lambda0)
+	y = HEX_EARTH_RADIUS * k * (math.Cos(phi0)*math.Sin(phi) - math.Sin(phi0)*math.Cos(phi)*math.Cos(lambda-lambda0))
+	return x, y
+}
+
+func laeaInverse(x, y float64) (lat, lng float64) {
+	phi0 := HEX_ORIGIN_LAT * math.Pi / 180
+	lambda0 := HEX_ORIGIN_LNG * math.Pi / 180
+
+	rho := math.Hypot(x, y)
+	if rho == 0 {
+		return HEX_ORIGIN_LAT, HEX_ORIGIN_LNG
+	}
+	c := 2 * math.Asin(rho/(2*HEX_EARTH_RADIUS))
+	phi := math.Asin(math.Cos(c)*math.Sin(phi0) + y*math.Sin(c)*math.Cos(phi0)/rho)
+	lambda := lambda0 + math.Atan2(x*math.Sin(c), rho*math.Cos(phi0)*math.Cos(c)-y*math.Sin(phi0)*math.Sin(c))
+	return phi * 180 / math.Pi, lambda * 180 / math.Pi
+}
+
+// Pointy top axial coordinates of the cell containing the point
+func hexCellAt(lat, lng float64, res int) HexCell {
+	x, y := laeaForward(lat, lng)
+
+	theta := hexRotation(res)
+	xr := x*math.Cos(-theta) - y*math.Sin(-theta)
+	yr := x*math.Sin(-theta) + y*math.Cos(-theta)
+
+	e := hexEdgeMeters(res)
+	qf := (math.Sqrt(3)/3*xr - yr/3) / e
+	rf := (2.0 / 3 * yr) / e
+
+	// cube rounding
+	sf := -qf - rf
+	q, r, s := math.Round(qf), math.Round(rf), math.Round(sf)
+	dq, dr, ds := math.Abs(q-qf), math.Abs(r-rf), math.Abs(s-sf)
+	if dq > dr && dq > ds {
+		q = -r - s
+	} else if dr > ds {
+		r = -q - s
+	}
+
+	return HexCell{Res: res, Q: int(q), R: int(r)}
+}
+
+func (h HexCell) centerXY() (x, y float64) {
+	e := hexEdgeMeters(h.Res)
+	xr := e * (math.Sqrt(3)*float64(h.Q) + math.Sqrt(3)/2*float64(h.R))
+	yr := e * (1.5 * float64(h.R))
+
+	theta := hexRotation(h.Res)
+	return xr*math.Cos(theta) - yr*math.Sin(theta), xr*math.Sin(theta) + yr*math.Cos(theta)
+}
+
+func (h HexCell) Center() (lat, lng float64) {
+	return laeaInverse(h.centerXY())
+}
+
+// Closed ring of [lng, lat] corners
+func (h HexCell) Boundary() []Position {
+	cx, cy := h.centerXY()
+	e := hexEdgeMeters(h.Res)
+	theta := hexRotation(h.Res)
+
+	ring := make([]Position, 7)
+	for i := range 6 {
+		angle := theta + float64(60*i-30)*math.Pi/180
+		lat, lng := laeaInverse(cx+e*math.Cos(angle), cy+e*math.Sin(angle))
+		ring[i] = Position{lng, lat}
+	}
+	ring[6] = ring[0]
+	return ring
+}
+
+func (h HexCell) Parent() (HexCell, bool) {
+	if h.Res == HEX_MIN_RES {
+		return HexCell{}, false
+	}
+	lat, lng := h.Center()
+	return hexCellAt(lat, lng, h.Res-1), true
+}
+
+// 4 bits of resolution then 30 bit two's complement q and r
+func (h HexCell) ID() string {
+	const mask = 1<<30 - 1
+	id := uint64(h.Res)<<60 | (uint64(h.Q)&mask)<<30 | uint64(h.R)&mask
+	return fmt.Sprintf("%016x", id)
+}
@@ -0,0 +1,55 @@
+package public
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHexCellRotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lng float64
+		res      int
+	}{
+		{"origin, resolution 1", HEX_ORIGIN_LAT, HEX_ORIGIN_LNG, 1},
+		{"tacoma, resolution 5", 47.2529, -122.4443, 5},
+		{"tacoma, resolution 9", 47.2529, -122.4443, 9},
+		{"seattle, resolution 10", 47.6062, -122.3321, 10},
+		{"miami, resolution 12", 25.7617, -80.1918, HEX_MAX_RES},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell := hexCellAt(tt.lat, tt.lng, tt.res)
+			e := hexEdgeMeters(tt.res)
+
+			// the cell holding its own center is itself
+			lat, lng := cell.Center()
+			if again := hexCellAt(lat, lng, tt.res); again != cell {
+				t.Errorf("center of %v falls in %v", cell, again)
+			}
+
+			// corners sit one edge from the center, so the rotated lattice and
+			// the rotated boundary agree
+			cx, cy := cell.centerXY()
+			for _, corner := range cell.Boundary()[:6] {
+				x, y := laeaForward(corner[1], corner[0])
+				if d := math.Hypot(x-cx, y-cy); math.Abs(d-e) > e*1e-6 {
+					t.Errorf("corner %v is %v m from the center, want %v", corner, d, e)
+				}
+			}
+
+			// the lattice turn puts the parent's center on a child center
+			parent, ok := cell.Parent()
+			if !ok {
+				t.Fatalf("no parent at resolution %d", tt.res)
+			}
+			px, py := parent.centerXY()
+			lat, lng = parent.Center()
+			child := hexCellAt(lat, lng, tt.res)
+			if x, y := child.centerXY(); math.Hypot(x-px, y-py) > e*1e-6 {
+				t.Errorf("parent center is %v m off the nearest child center", math.Hypot(x-px, y-py))
+			}
+		})
+	}
+}
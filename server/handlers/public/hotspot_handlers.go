@@ -0,0 +1,314 @@
+package public
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const HOTSPOT_MAX_CELLS = 40000
+
+type HotSpot struct {
+	Name       string  `json:"name,omitempty"`
+	Count      int     `json:"count"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	ZScore     float64 `json:"z_score"`
+	PValue     float64 `json:"p_value"`
+	Class      string  `json:"class"`
+	Confidence int     `json:"confidence"`
+}
+
+// hot or cold at 90, 95 or 99 percent confidence
+func classifyZScore(z float64) (string, int) {
+	abs := math.Abs(z)
+	confidence := 0
+	switch {
+	case abs >= 2.576:
+		confidence = 99
+	case abs >= 1.960:
+		confidence = 95
+	case abs >= 1.645:
+		confidence = 90
+	default:
+		return "not_significant", 0
+	}
+	if z > 0 {
+		return "hot", confidence
+	}
+	return "cold", confidence
+}
+
+// Getis-Ord Gi* with self included binary weights. lag(i) returns the sum of
+// the values within i's neighborhood, i itself included, and how many there are.
+func getisOrdGiStar(values []float64, lag func(i int) (sum, w float64)) []float64 {
+	n := float64(len(values))
+	var sum, sumSq float64
+	for _, v := range values {
+		sum += v
+		sumSq += v * v
+	}
+	mean := sum / n
+	s := math.Sqrt(sumSq/n - mean*mean)
+
+	z := make([]float64, len(values))
+	if s == 0 || n < 2 {
+		return z
+	}
+	for i := range values {
+		lagSum, w := lag(i)
+		denom := s * math.Sqrt((n*w-w*w)/(n-1))
+		if denom > 0 {
+			z[i] = (lagSum - mean*w) / denom
+		}
+	}
+	return z
+}
+
+// Statistically significant hot and cold spots (Getis-Ord Gi*).
+// unit=grid uses square cells of cell_size meters with a distance band,
+// unit=neighborhood uses each neighborhood's k nearest neighbors.
+func (h *Handler) GetHotSpots(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("unit", "grid") {
+	case "grid":
+		h.gridHotSpots(c, filters)
+	case "neighborhood":
+		h.neighborhoodHotSpots(c, filters)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unit must be grid or neighborhood"})
+	}
+}
+
+func (h *Handler) gridHotSpots(c *gin.Context, filters CrimeFilters) {
+	cellSize, err := strconv.ParseFloat(c.DefaultQuery("cell_size", "250"), 64)
+	if err != nil || cellSize < 50 || cellSize > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cell_size must be between 50 and 5000 meters"})
+		return
+	}
+
+	distance, err := strconv.ParseFloat(c.DefaultQuery("distance", strconv.FormatFloat(cellSize*1.5, 'f', -1, 64)), 64)
+	if err != nil || distance < cellSize || distance > cellSize*10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "distance must be between cell_size and 10 times cell_size"})
+		return
+	}
+
+	counts, err := h.getLocationCounts(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to compute hot spots",
+		})
+		return
+	}
+
+	response := gin.H{
+		"unit":        "grid",
+		"cell_size_m": cellSize,
+		"distance_m":  distance,
+		"year":        filters.Years,
+		"crime_type":  filters.CrimeTypes,
+	}
+	if len(counts) == 0 {
+		response["cells"] = newFeatureCollection()
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	proj := projectionFor(counts)
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	xs := make([]float64, len(counts))
+	ys := make([]float64, len(counts))
+	for i, lc := range counts {
+		xs[i], ys[i] = proj.toXY(lc.Latitude, lc.Longitude)
+		minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+
+	cols := int((maxX-minX)/cellSize) + 1
+	rows := int((maxY-minY)/cellSize) + 1
+	if cols*rows > HOTSPOT_MAX_CELLS {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "area is too large for this cell_size, use a larger cell_size or narrower filters"})
+		return
+	}
+
+	values := make([]float64, cols*rows)
+	for i, lc := range counts {
+		col := int((xs[i] - minX) / cellSize)
+		row := int((ys[i] - minY) / cellSize)
+		values[row*cols+col] += float64(lc.Count)
+	}
+
+	// offsets of the cell centers within the distance band, zero count cells
+	// take part too
+	reach := int(distance / cellSize)
+	var stencil [][2]int
+	for dr := -reach; dr <= reach; dr++ {
+		for dc := -reach; dc <= reach; dc++ {
+			if math.Hypot(float64(dr), float64(dc))*cellSize <= distance {
+				stencil = append(stencil, [2]int{dr, dc})
+			}
+		}
+	}
+	lag := func(i int) (sum, w float64) {
+		row, col := i/cols, i%cols
+		for _, o := range stencil {
+			r2, c2 := row+o[0], col+o[1]
+			if r2 < 0 || c2 < 0 || r2 >= rows || c2 >= cols {
+				continue
+			}
+			sum += values[r2*cols+c2]
+			w++
+		}
+		return sum, w
+	}
+
+	zScores := getisOrdGiStar(values, lag)
+
+	cells := newFeatureCollection()
+	summary := map[string]int{"hot": 0, "cold": 0, "not_significant": 0}
+	for i, z := range zScores {
+		class, confidence := classifyZScore(z)
+		summary[class]++
+		if values[i] == 0 && class == "not_significant" {
+			continue
+		}
+
+		row, col := i/cols, i%cols
+		x0, y0 := minX+float64(col)*cellSize, minY+float64(row)*cellSize
+		ring := make([]Position, 0, 5)
+		for _, corner := range [][2]float64{{x0, y0}, {x0 + cellSize, y0}, {x0 + cellSize, y0 + cellSize}, {x0, y0 + cellSize}, {x0, y0}} {
+			lat, lng := proj.toLatLng(corner[0], corner[1])
+			ring = append(ring, Position{lng, lat})
+		}
+		lat, lng := proj.toLatLng(x0+cellSize/2, y0+cellSize/2)
+
+		cells.Features = append(cells.Features, polygonFeature(Polygon{ring}, HotSpot{
+			Count:      int(values[i]),
+			Latitude:   lat,
+			Longitude:  lng,
+			ZScore:     z,
+			PValue:     twoSidedPValue(z),
+			Class:      class,
+			Confidence: confidence,
+		}))
+	}
+
+	response["cells"] = cells
+	response["total_cells"] = len(values)
+	response["summary"] = summary
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) neighborhoodHotSpots(c *gin.Context, filters CrimeFilters) {
+	k, err := strconv.Atoi(c.DefaultQuery("k", "5"))
+	if err != nil || k < 1 || k > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "k must be between 1 and 20"})
+		return
+	}
+
+	// every neighborhood with addresses in the cities with incidents takes
+	// part, those without any count as zero. Positions are the centers of
+	// their addresses.
+	counts := `
+			SELECT a.neighborhood_id, a.city_id, COUNT(*) as count
+			` + crimeDumpFrom
+	counts, args := filters.appendWhere(counts, []any{})
+	query := `
+		WITH counts AS (` + counts + `
+			GROUP BY a.neighborhood_id, a.city_id
+		),
+		centers AS (
+			SELECT a.neighborhood_id, AVG(l.latitude)::float8 as latitude, AVG(l.longitude)::float8 as longitude
+			FROM addresses a
+			JOIN locations l ON a.location_id = l.location_id
+			WHERE a.neighborhood_id IS NOT NULL
+			AND a.city_id IN (SELECT city_id FROM counts)
+			GROUP BY a.neighborhood_id
+		)
+		SELECT
+			n.neighborhood_name,
+			COALESCE(SUM(counts.count), 0)::int as count,
+			AVG(centers.latitude)::float8 as latitude,
+			AVG(centers.longitude)::float8 as longitude
+		FROM neighborhoods n
+		JOIN centers ON centers.neighborhood_id = n.neighborhood_id
+		LEFT JOIN counts ON counts.neighborhood_id = n.neighborhood_id
+		GROUP BY n.neighborhood_name
+	`
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying neighborhood hot spots: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to compute hot spots",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var areas []HotSpot
+	for rows.Next() {
+		var area HotSpot
+		if err := rows.Scan(&area.Name, &area.Count, &area.Latitude, &area.Longitude); err != nil {
+			log.Printf("Error scanning neighborhood hot spot: %v", err)
+			continue
+		}
+		areas = append(areas, area)
+	}
+
+	k = min(k, max(len(areas)-1, 0))
+	values := make([]float64, len(areas))
+	neighbors := make([][]int, len(areas))
+	for i, a := range areas {
+		values[i] = float64(a.Count)
+
+		others := make([]int, 0, len(areas))
+		for j := range areas {
+			if j != i {
+				others = append(others, j)
+			}
+		}
+		sort.Slice(others, func(x, y int) bool {
+			dx := haversineDistanceMiles(a.Latitude, a.Longitude, areas[others[x]].Latitude, areas[others[x]].Longitude)
+			dy := haversineDistanceMiles(a.Latitude, a.Longitude, areas[others[y]].Latitude, areas[others[y]].Longitude)
+			return dx < dy
+		})
+		neighbors[i] = append([]int{i}, others[:k]...)
+	}
+
+	zScores := getisOrdGiStar(values, func(i int) (sum, w float64) {
+		for _, j := range neighbors[i] {
+			sum += values[j]
+		}
+		return sum, float64(len(neighbors[i]))
+	})
+	for i, z := range zScores {
+		areas[i].ZScore = z
+		areas[i].PValue = twoSidedPValue(z)
+		areas[i].Class, areas[i].Confidence = classifyZScore(z)
+	}
+
+	sort.Slice(areas, func(i, j int) bool {
+		return areas[i].ZScore > areas[j].ZScore
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"unit":       "neighborhood",
+		"k":          k,
+		"areas":      areas,
+		"count":      len(areas),
+		"year":       filters.Years,
+		"crime_type": filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,287 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const INTERVENTION_MAX_DAYS = 730
+
+// Target and control areas with the windows either side of the intervention.
+// control is nil when the rest of the filtered area serves as the control.
+type intervention struct {
+	target  string
+	control *string
+	date    time.Time
+	days    int
+	pre     dateRange
+	post    dateRange
+}
+
+type RegionCounts struct {
+	Pre  int `json:"pre"`
+	Post int `json:"post"`
+}
+
+// Relative difference-in-differences, the change in the target over the
+// change in the control. Effect is the post count against the expected count
+// had the target followed the control.
+type DiDEstimate struct {
+	RateRatio     float64 `json:"rate_ratio"`
+	PercentChange float64 `json:"percent_change"`
+	PercentLow    float64 `json:"percent_low"`
+	PercentHigh   float64 `json:"percent_high"`
+	Expected      float64 `json:"expected"`
+	Effect        float64 `json:"effect"`
+	EffectLow     float64 `json:"effect_low"`
+	EffectHigh    float64 `json:"effect_high"`
+	PValue        float64 `json:"p_value"`
+	Significant   bool    `json:"significant"`
+	Corrected     bool    `json:"corrected"`
+}
+
+// Ratio of ratios with a log scale Poisson interval. Zero counts get the
+// Haldane 0.5 correction so the ratio stays finite, flagged as corrected.
+func differenceInDifferences(target, control RegionCounts, z float64) DiDEstimate {
+	tPre, tPost := float64(target.Pre), float64(target.Post)
+	cPre, cPost := float64(control.Pre), float64(control.Post)
+	corrected := tPre == 0 || tPost == 0 || cPre == 0 || cPost == 0
+	if corrected {
+		tPre, tPost, cPre, cPost = tPre+0.5, tPost+0.5, cPre+0.5, cPost+0.5
+	}
+
+	logRatio := math.Log(tPost/tPre) - math.Log(cPost/cPre)
+	se := math.Sqrt(1/tPre + 1/tPost + 1/cPre + 1/cPost)
+	low, high := math.Exp(logRatio-z*se), math.Exp(logRatio+z*se)
+
+	est := DiDEstimate{
+		RateRatio:     math.Exp(logRatio),
+		PercentChange: (math.Exp(logRatio) - 1) * 100,
+		PercentLow:    (low - 1) * 100,
+		PercentHigh:   (high - 1) * 100,
+		Expected:      tPre * cPost / cPre,
+		PValue:        twoSidedPValue(logRatio / se),
+		Corrected:     corrected,
+	}
+	// from the same corrected counts as the ratio, so the interval holds the
+	// point estimate
+	est.Effect = tPost - est.Expected
+	est.EffectLow = tPost - tPost/low
+	est.EffectHigh = tPost - tPost/high
+	est.Significant = est.PValue < 0.05
+	return est
+}
+
+func parsePolygonParam(raw []byte, name string) (string, error) {
+	polygon, err := parseGeoJSONPolygon(raw)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return polygon.GeoJSON()
+}
+
+// Reads target and control polygons from the query or a POST body of
+// {"target": ..., "control": ...}, and date with days either side of it
+func parseIntervention(c *gin.Context) (intervention, error) {
+	var iv intervention
+
+	var body struct {
+		Target  json.RawMessage `json:"target"`
+		Control json.RawMessage `json:"control"`
+	}
+	if c.Request.Method == http.MethodPost {
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return iv, fmt.Errorf("failed to read body")
+		}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			return iv, fmt.Errorf("body must be {\"target\": <GeoJSON>, \"control\": <GeoJSON>}")
+		}
+	}
+	if target := c.Query("target"); target != "" {
+		body.Target = json.RawMessage(target)
+	}
+	if control := c.Query("control"); control != "" {
+		body.Control = json.RawMessage(control)
+	}
+
+	if len(body.Target) == 0 {
+		return iv, fmt.Errorf("target polygon is required")
+	}
+	target, err := parsePolygonParam(body.Target, "target")
+	if err != nil {
+		return iv, err
+	}
+	iv.target = target
+	if len(body.Control) > 0 && string(body.Control) != "null" {
+		control, err := parsePolygonParam(body.Control, "control")
+		if err != nil {
+			return iv, err
+		}
+		iv.control = &control
+	}
+
+	iv.date, err = time.Parse(time.DateOnly, c.Query("date"))
+	if err != nil {
+		return iv, fmt.Errorf("date of the intervention is required as YYYY-MM-DD")
+	}
+	iv.days, err = strconv.Atoi(c.DefaultQuery("days", "180"))
+	if err != nil || iv.days < 7 || iv.days > INTERVENTION_MAX_DAYS {
+		return iv, fmt.Errorf("days must be between 7 and %d", INTERVENTION_MAX_DAYS)
+	}
+
+	// the intervention day starts the post window
+	iv.pre = dateRange{
+		Start: iv.date.AddDate(0, 0, -iv.days).Format(time.DateOnly),
+		End:   iv.date.AddDate(0, 0, -1).Format(time.DateOnly),
+		Days:  iv.days,
+	}
+	iv.post = dateRange{
+		Start: iv.date.Format(time.DateOnly),
+		End:   iv.date.AddDate(0, 0, iv.days-1).Format(time.DateOnly),
+		Days:  iv.days,
+	}
+	return iv, nil
+}
+
+// Crime filters, intervention and interval z shared by the intervention and
+// displacement endpoints, writing the error response when one fails
+func interventionParams(c *gin.Context, example string) (filters CrimeFilters, iv intervention, z float64, ok bool) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filters, iv, 0, false
+	}
+
+	iv, err = parseIntervention(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"example": example,
+		})
+		return filters, iv, 0, false
+	}
+
+	z, err = parseInterval(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filters, iv, 0, false
+	}
+
+	// the windows take the place of the year and date filters
+	filters.Years = nil
+	filters.StartDate = ""
+	filters.EndDate = ""
+	return filters, iv, z, true
+}
+
+// Pre and post counts for the target, control and, with buffer meters set, the
+// ring around the target. Without a control polygon everything else that
+// matches the filters is the control.
+func (h *Handler) getRegionCounts(filters CrimeFilters, iv intervention, buffer float64) (map[string]RegionCounts, error) {
+	inTarget := "ST_Covers(ST_GeomFromGeoJSON($1)::geography, l.geog)"
+	args := []any{iv.target, iv.pre.Start, iv.post.Start, iv.post.End}
+
+	inBuffer := "FALSE"
+	if buffer > 0 {
+		args = append(args, buffer)
+		inBuffer = fmt.Sprintf("ST_DWithin(ST_GeomFromGeoJSON($1)::geography, l.geog, $%d)", len(args))
+	}
+
+	inControl := "TRUE"
+	if iv.control != nil {
+		args = append(args, *iv.control)
+		inControl = fmt.Sprintf("ST_Covers(ST_GeomFromGeoJSON($%d)::geography, l.geog)", len(args))
+	}
+
+	// first match wins, so the buffer excludes the target and the control
+	// excludes both
+	query := `
+		SELECT
+			CASE WHEN ` + inTarget + ` THEN 'target'
+				WHEN ` + inBuffer + ` THEN 'buffer'
+				WHEN ` + inControl + ` THEN 'control' END as region,
+			ci.incident_date < $3::date as pre,
+			COUNT(*) as count
+		` + crimeDumpFrom + `
+		AND ci.incident_date BETWEEN $2 AND $4
+	`
+	if iv.control != nil {
+		query += " AND (" + inTarget + " OR " + inBuffer + " OR " + inControl + ")"
+	}
+	query, args = filters.appendWhere(query, args)
+	query += " GROUP BY region, pre"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying region counts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := map[string]RegionCounts{"target": {}, "control": {}}
+	if buffer > 0 {
+		regions["buffer"] = RegionCounts{}
+	}
+	for rows.Next() {
+		var region *string
+		var pre bool
+		var count int
+		if err := rows.Scan(&region, &pre, &count); err != nil {
+			log.Printf("Error scanning region counts: %v", err)
+			continue
+		}
+		if region == nil {
+			continue
+		}
+		counts := regions[*region]
+		if pre {
+			counts.Pre += count
+		} else {
+			counts.Post += count
+		}
+		regions[*region] = counts
+	}
+	return regions, nil
+}
+
+// Before and after comparison of a target area against a control, e.g. new
+// street lighting or a patrol on a corridor. Windows of days either side of
+// date are compared, date itself starts the post window. Without a control
+// polygon the rest of the filtered area is the control. Takes the usual crime
+// filters other than dates.
+func (h *Handler) GetInterventionImpact(c *gin.Context) {
+	filters, iv, z, ok := interventionParams(c, "POST /api/public/crimes/intervention?date=2025-03-01&days=180 with {\"target\": <GeoJSON polygon>}")
+	if !ok {
+		return
+	}
+
+	regions, err := h.getRegionCounts(filters, iv, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to estimate intervention impact",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":         iv.date.Format(time.DateOnly),
+		"pre":          iv.pre,
+		"post":         iv.post,
+		"target":       regions["target"],
+		"control":      regions["control"],
+		"control_area": iv.control != nil,
+		"estimate":     differenceInDifferences(regions["target"], regions["control"], z),
+		"interval":     c.DefaultQuery("interval", "95"),
+		"crime_type":   filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,44 @@
+package public
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDifferenceInDifferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      RegionCounts
+		control     RegionCounts
+		ratio       float64
+		corrected   bool
+		significant bool
+	}{
+		{"no change", RegionCounts{100, 100}, RegionCounts{100, 100}, 1, false, false},
+		{"target halved", RegionCounts{200, 100}, RegionCounts{200, 200}, 0.5, false, true},
+		{"target followed control", RegionCounts{100, 150}, RegionCounts{200, 300}, 1, false, false},
+		{"zero target pre", RegionCounts{0, 10}, RegionCounts{50, 50}, 21, true, true},
+		{"zero control post", RegionCounts{20, 20}, RegionCounts{5, 0}, 11, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est := differenceInDifferences(tt.target, tt.control, 1.96)
+			if math.Abs(est.RateRatio-tt.ratio) > 1e-9 {
+				t.Errorf("rate ratio %v, want %v", est.RateRatio, tt.ratio)
+			}
+			if est.Corrected != tt.corrected {
+				t.Errorf("corrected %v, want %v", est.Corrected, tt.corrected)
+			}
+			if est.Significant != tt.significant {
+				t.Errorf("significant %v (p %v), want %v", est.Significant, est.PValue, tt.significant)
+			}
+			if est.PercentLow > est.PercentChange || est.PercentChange > est.PercentHigh {
+				t.Errorf("percent change %v outside [%v, %v]", est.PercentChange, est.PercentLow, est.PercentHigh)
+			}
+			if est.EffectLow > est.Effect || est.Effect > est.EffectHigh {
+				t.Errorf("effect %v outside [%v, %v]", est.Effect, est.EffectLow, est.EffectHigh)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package public
+
+import (
+	"math"
+	"slices"
+)
+
+const EARTH_RADIUS_M = EARTH_RADIUS_MILES * METERS_PER_MILE
+
+// Flat local projection around an origin, fine at city scale
+type localProjection struct {
+	lat0, lng0 float64
+	cosLat0    float64
+}
+
+func newLocalProjection(lat0, lng0 float64) localProjection {
+	return localProjection{lat0: lat0, lng0: lng0, cosLat0: math.Cos(lat0 * math.Pi / 180)}
+}
+
+func (p localProjection) toXY(lat, lng float64) (x, y float64) {
+	x = (lng - p.lng0) * math.Pi / 180 * EARTH_RADIUS_M * p.cosLat0
+	y = (lat - p.lat0) * math.Pi / 180 * EARTH_RADIUS_M
+	return x, y
+}
+
+func (p localProjection) toLatLng(x, y float64) (lat, lng float64) {
+	lat = p.lat0 + y/EARTH_RADIUS_M*180/math.Pi
+	lng = p.lng0 + x/(EARTH_RADIUS_M*p.cosLat0)*180/math.Pi
+	return lat, lng
+}
+
+// Projection centered on the weighted mean of the counts
+func projectionFor(counts []locationCount) localProjection {
+	var lat, lng, total float64
+	for _, lc := range counts {
+		lat += lc.Latitude * float64(lc.Count)
+		lng += lc.Longitude * float64(lc.Count)
+		total += float64(lc.Count)
+	}
+	if total == 0 {
+		return newLocalProjection(47.2529, -122.4443)
+	}
+	return newLocalProjection(lat/total, lng/total)
+}
+
+type densityKernel struct {
+	name    string
+	support float64 // in bandwidths
+	weight  func(d2, h float64) float64
+}
+
+var densityKernels = map[string]densityKernel{
+	"gaussian": {
+		name:    "gaussian",
+		support: 3,
+		weight: func(d2, h float64) float64 {
+			return math.Exp(-d2/(2*h*h)) / (2 * math.Pi * h * h)
+		},
+	},
+	"quartic": {
+		name:    "quartic",
+		support: 1,
+		weight: func(d2, h float64) float64 {
+			if d2 >= h*h {
+				return 0
+			}
+			u := 1 - d2/(h*h)
+			return 3 / (math.Pi * h * h) * u * u
+		},
+	},
+}
+
+// Density surface in incidents per square kilometer. Row 0 is the southern
+// edge, values are sampled at cell centers.
+type DensityGrid struct {
+	Kernel    string      `json:"kernel"`
+	Bandwidth float64     `json:"bandwidth_m"`
+	CellSize  float64     `json:"cell_size_m"`
+	Rows      int         `json:"rows"`
+	Cols      int         `json:"cols"`
+	Bounds    BoundingBox `json:"bbox"`
+	Max       float64     `json:"max"`
+	Values    [][]float64 `json:"values"`
+
+	proj       localProjection
+	minX, minY float64
+}
+
+const (
+	DENSITY_MAX_CELLS = 250000
+	// kernel evaluations per request, cells under each location's reach
+	// summed over the locations
+	DENSITY_MAX_WORK = 50_000_000
+)
+
+// An empty grid, no rows or columns, when counts is empty
+func estimateDensity(counts []locationCount, kernel densityKernel, bandwidth, cellSize float64) *DensityGrid {
+	if len(counts) == 0 {
+		return &DensityGrid{Kernel: kernel.name, Bandwidth: bandwidth, CellSize: cellSize, Values: [][]float64{}}
+	}
+
+	proj := projectionFor(counts)
+	xs := make([]float64, len(counts))
+	ys := make([]float64, len(counts))
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i, lc := range counts {
+		xs[i], ys[i] = proj.toXY(lc.Latitude, lc.Longitude)
+		minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+
+	reach := kernel.support * bandwidth
+	minX, minY = minX-reach, minY-reach
+	maxX, maxY = maxX+reach, maxY+reach
+
+	// coarsen rather than refuse when the extent is large or the kernels
+	// cover too many cells, once a cell is wider than the kernel only cell
+	// count matters
+	work := func(cellSize float64) float64 {
+		span := math.Floor(2*reach/cellSize) + 2
+		return span * span * float64(len(counts))
+	}
+	for math.Ceil((maxX-minX)/cellSize)*math.Ceil((maxY-minY)/cellSize) > DENSITY_MAX_CELLS ||
+		(work(cellSize) > DENSITY_MAX_WORK && cellSize < 2*reach) {
+		cellSize *= 1.25
+	}
+	cols := int(math.Ceil((maxX - minX) / cellSize))
+	rows := int(math.Ceil((maxY - minY) / cellSize))
+
+	values := make([][]float64, rows)
+	for r := range values {
+		values[r] = make([]float64, cols)
+	}
+
+	for i, lc := range counts {
+		c0 := max(0, int((xs[i]-reach-minX)/cellSize))
+		c1 := min(cols-1, int((xs[i]+reach-minX)/cellSize))
+		r0 := max(0, int((ys[i]-reach-minY)/cellSize))
+		r1 := min(rows-1, int((ys[i]+reach-minY)/cellSize))
+		for r := r0; r <= r1; r++ {
+			cy := minY + (float64(r)+0.5)*cellSize
+			for col := c0; col <= c1; col++ {
+				cx := minX + (float64(col)+0.5)*cellSize
+				d2 := (cx-xs[i])*(cx-xs[i]) + (cy-ys[i])*(cy-ys[i])
+				values[r][col] += float64(lc.Count) * kernel.weight(d2, bandwidth)
+			}
+		}
+	}
+
+	grid := &DensityGrid{
+		Kernel:    kernel.name,
+		Bandwidth: bandwidth,
+		CellSize:  cellSize,
+		Rows:      rows,
+		Cols:      cols,
+		Values:    values,
+		proj:      proj,
+		minX:      minX,
+		minY:      minY,
+	}
+
+	// per square meter to per square kilometer
+	for r := range values {
+		for col := range values[r] {
+			values[r][col] *= 1e6
+			grid.Max = math.Max(grid.Max, values[r][col])
+		}
+	}
+
+	south, west := proj.toLatLng(minX, minY)
+	north, east := proj.toLatLng(minX+float64(cols)*cellSize, minY+float64(rows)*cellSize)
+	grid.Bounds = BoundingBox{MinLng: west, MinLat: south, MaxLng: east, MaxLat: north}
+
+	return grid
+}
+
+// Grid position (column, row in cell units from the first cell center) to [lng, lat]
+func (g *DensityGrid) position(col, row float64) Position {
+	lat, lng := g.proj.toLatLng(g.minX+(col+0.5)*g.CellSize, g.minY+(row+0.5)*g.CellSize)
+	return Position{lng, lat}
+}
+
+// Areas at or above level as polygons, the isolines from marching squares
+// with the low areas inside them as holes. The grid is treated as zero outside
+// its edges so every line closes into a ring.
+func (g *DensityGrid) Contours(level float64) []Polygon {
+	return nestRings(g.isolines(level))
+}
+
+// Closed isolines at level using marching squares
+func (g *DensityGrid) isolines(level float64) [][]Position {
+	value := func(r, c int) float64 {
+		if r < 0 || c < 0 || r >= g.Rows || c >= g.Cols {
+			return 0
+		}
+		return g.Values[r][c]
+	}
+
+	// edges are keyed by the lower left corner of the cell edge and direction
+	type edge struct {
+		r, c       int
+		horizontal bool
+	}
+	crossing := func(e edge) Position {
+		r2, c2 := e.r, e.c+1
+		if !e.horizontal {
+			r2, c2 = e.r+1, e.c
+		}
+		v1, v2 := value(e.r, e.c), value(r2, c2)
+		t := 0.5
+		if v2 != v1 {
+			t = (level - v1) / (v2 - v1)
+		}
+		return g.position(float64(e.c)+t*float64(c2-e.c), float64(e.r)+t*float64(r2-e.r))
+	}
+
+	links := make(map[edge][]edge)
+	link := func(a, b edge) {
+		links[a] = append(links[a], b)
+		links[b] = append(links[b], a)
+	}
+
+	for r := -1; r < g.Rows; r++ {
+		for c := -1; c < g.Cols; c++ {
+			bottom := edge{r, c, true}
+			top := edge{r + 1, c, true}
+			left := edge{r, c, false}
+			right := edge{r, c + 1, false}
+
+			idx := 0
+			if value(r, c) >= level {
+				idx |= 1
+			}
+			if value(r, c+1) >= level {
+				idx |= 2
+			}
+			if value(r+1, c+1) >= level {
+				idx |= 4
+			}
+			if value(r+1, c) >= level {
+				idx |= 8
+			}
+
+			switch idx {
+			case 1, 14:
+				link(left, bottom)
+			case 2, 13:
+				link(bottom, right)
+			case 3, 12:
+				link(left, right)
+			case 4, 11:
+				link(right, top)
+			case 6, 9:
+				link(bottom, top)
+			case 7, 8:
+				link(left, top)
+			case 5, 10:
+				// saddle, resolved with the cell center average
+				center := (value(r, c) + value(r, c+1) + value(r+1, c+1) + value(r+1, c)) / 4
+				if (center >= level) == (idx == 5) {
+					link(left, top)
+					link(bottom, right)
+				} else {
+					link(left, bottom)
+					link(right, top)
+				}
+			}
+		}
+	}
+
+	var rings [][]Position
+	visited := make(map[edge]bool)
+	for start := range links {
+		if visited[start] {
+			continue
+		}
+		ring := []Position{crossing(start)}
+		visited[start] = true
+		prev, cur := start, links[start][0]
+		for cur != start && !visited[cur] {
+			visited[cur] = true
+			ring = append(ring, crossing(cur))
+			next := links[cur][0]
+			if next == prev && len(links[cur]) > 1 {
+				next = links[cur][1]
+			}
+			prev, cur = cur, next
+		}
+		if len(ring) >= 3 {
+			rings = append(rings, append(ring, ring[0]))
+		}
+	}
+	return rings
+}
+
+// Isolines at one level never cross, so a ring inside an odd number of others
+// bounds a low area, a hole in the smallest ring around it, and the rest are
+// exteriors. Exteriors wind counterclockwise and holes clockwise, as GeoJSON
+// expects.
+func nestRings(rings [][]Position) []Polygon {
+	depth := make([]int, len(rings))
+	parent := make([]int, len(rings))
+	for i := range rings {
+		parent[i] = -1
+		for j := range rings {
+			if i == j || !ringContains(rings[j], rings[i][0]) {
+				continue
+			}
+			depth[i]++
+			if parent[i] < 0 || math.Abs(ringArea(rings[j])) < math.Abs(ringArea(rings[parent[i]])) {
+				parent[i] = j
+			}
+		}
+	}
+
+	polygons := []Polygon{}
+	index := make(map[int]int)
+	for i, ring := range rings {
+		if depth[i]%2 == 0 {
+			index[i] = len(polygons)
+			polygons = append(polygons, Polygon{orientRing(ring, true)})
+		}
+	}
+	for i, ring := range rings {
+		if depth[i]%2 == 1 {
+			p := index[parent[i]]
+			polygons[p] = append(polygons[p], orientRing(ring, false))
+		}
+	}
+	return polygons
+}
+
+// Ray casting on [lng, lat], fine for the small extent of a density grid
+func ringContains(ring []Position, p Position) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Shoelace area in degrees, positive when counterclockwise
+func ringArea(ring []Position) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+func orientRing(ring []Position, counterclockwise bool) []Position {
+	if (ringArea(ring) > 0) != counterclockwise {
+		slices.Reverse(ring)
+	}
+	return ring
+}
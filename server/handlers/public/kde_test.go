@@ -0,0 +1,64 @@
+package public
+
+import "testing"
+
+// size by size grid of value with a block of inner from cell lo to hi on both axes
+func squareGrid(size, lo, hi int, value, inner float64) *DensityGrid {
+	values := make([][]float64, size)
+	for r := range values {
+		values[r] = make([]float64, size)
+		for c := range values[r] {
+			values[r][c] = value
+			if r >= lo && r <= hi && c >= lo && c <= hi {
+				values[r][c] = inner
+			}
+		}
+	}
+	return &DensityGrid{
+		CellSize: 50,
+		Rows:     size,
+		Cols:     size,
+		Values:   values,
+		proj:     newLocalProjection(47.25, -122.44),
+	}
+}
+
+func TestContours(t *testing.T) {
+	tests := []struct {
+		name     string
+		grid     *DensityGrid
+		level    float64
+		polygons int
+		holes    int
+	}{
+		{"empty", estimateDensity(nil, densityKernels["quartic"], 300, 50), 1, 0, 0},
+		{"single peak", squareGrid(7, 2, 4, 0, 10), 5, 1, 0},
+		{"hot ring around a cold center", squareGrid(7, 2, 4, 10, 0), 5, 1, 1},
+		{"peak inside the cold center", func() *DensityGrid {
+			g := squareGrid(9, 2, 6, 10, 0)
+			g.Values[4][4] = 10
+			return g
+		}(), 5, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polygons := tt.grid.Contours(tt.level)
+			holes := 0
+			for _, polygon := range polygons {
+				if ringArea(polygon[0]) <= 0 {
+					t.Errorf("exterior ring is not counterclockwise")
+				}
+				for _, hole := range polygon[1:] {
+					if ringArea(hole) >= 0 {
+						t.Errorf("hole is not clockwise")
+					}
+					holes++
+				}
+			}
+			if len(polygons) != tt.polygons || holes != tt.holes {
+				t.Errorf("got %d polygons and %d holes, want %d and %d", len(polygons), holes, tt.polygons, tt.holes)
+			}
+		})
+	}
+}
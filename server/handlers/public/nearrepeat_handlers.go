@@ -0,0 +1,221 @@
+package public
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	NEAR_REPEAT_MAX_INCIDENTS = 20000
+	NEAR_REPEAT_MAX_PAIRS     = 500000
+)
+
+// One space by time band of the Knox table
+type NearRepeatBand struct {
+	DistanceFrom float64  `json:"distance_from_m"`
+	DistanceTo   float64  `json:"distance_to_m"`
+	DaysFrom     int      `json:"days_from"`
+	DaysTo       int      `json:"days_to"`
+	Observed     int      `json:"observed"`
+	Expected     float64  `json:"expected"`
+	Ratio        *float64 `json:"ratio"`
+	PValue       float64  `json:"p_value"`
+}
+
+// Incident pair close enough in space to land in one of the bands
+type spacePair struct {
+	i, j int
+	band int
+}
+
+// Pairs within bands*spaceBand meters, found through an index of cells that
+// size so only neighbouring cells are compared
+func nearPairs(points []incidentPoint, spaceBand float64, bands int) ([]spacePair, error) {
+	reach := spaceBand * float64(bands)
+
+	type bucket struct{ x, y int }
+	grid := make(map[bucket][]int)
+	for i, p := range points {
+		b := bucket{int(math.Floor(p.x / reach)), int(math.Floor(p.y / reach))}
+		grid[b] = append(grid[b], i)
+	}
+
+	var pairs []spacePair
+	for i, p := range points {
+		bx, by := int(math.Floor(p.x/reach)), int(math.Floor(p.y/reach))
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[bucket{bx + dx, by + dy}] {
+					if j <= i {
+						continue
+					}
+					d := math.Hypot(points[j].x-p.x, points[j].y-p.y)
+					if d > reach {
+						continue
+					}
+					pairs = append(pairs, spacePair{i: i, j: j, band: min(int(d/spaceBand), bands-1)})
+					if len(pairs) > NEAR_REPEAT_MAX_PAIRS {
+						return nil, fmt.Errorf("more than %d incident pairs are within range, narrow the filters or bands", NEAR_REPEAT_MAX_PAIRS)
+					}
+				}
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// Pair counts by space band then time band for the given incident days
+func knoxTable(pairs []spacePair, days []int, timeBand, bands int) [][]int {
+	table := make([][]int, bands)
+	for s := range table {
+		table[s] = make([]int, bands)
+	}
+	for _, pair := range pairs {
+		gap := days[pair.i] - days[pair.j]
+		if gap < 0 {
+			gap = -gap
+		}
+		if t := gap / timeBand; t < bands {
+			table[pair.band][t]++
+		}
+	}
+	return table
+}
+
+// Near repeat calculator. Compares how many incident pairs fall close in both
+// space and time against the count expected if the dates were unrelated to
+// location, using a Knox test with Monte Carlo permutation of the dates.
+func (h *Handler) GetNearRepeat(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if category := splitParam(c.Query("category")); len(category) > 0 {
+		filters.CrimeTypes = category
+	}
+	if len(filters.CrimeTypes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category is required"})
+		return
+	}
+
+	spaceBand, err := strconv.ParseFloat(c.DefaultQuery("space_band", "100"), 64)
+	if err != nil || spaceBand < 10 || spaceBand > 2000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "space_band must be between 10 and 2000 meters"})
+		return
+	}
+
+	timeBand, err := strconv.Atoi(c.DefaultQuery("time_band", "7"))
+	if err != nil || timeBand < 1 || timeBand > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time_band must be between 1 and 90 days"})
+		return
+	}
+
+	bands, err := strconv.Atoi(c.DefaultQuery("bands", "5"))
+	if err != nil || bands < 1 || bands > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bands must be between 1 and 10"})
+		return
+	}
+
+	iterations, err := strconv.Atoi(c.DefaultQuery("iterations", "99"))
+	if err != nil || iterations < 19 || iterations > 999 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "iterations must be between 19 and 999"})
+		return
+	}
+
+	points, err := h.getIncidentPoints(filters, NEAR_REPEAT_MAX_INCIDENTS)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to run near repeat analysis",
+		})
+		return
+	}
+	if len(points) > NEAR_REPEAT_MAX_INCIDENTS {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("more than %d incidents match, narrow the filters", NEAR_REPEAT_MAX_INCIDENTS),
+		})
+		return
+	}
+
+	days := make([]int, 0, len(points))
+	located := points[:0]
+	for _, p := range points {
+		date, err := time.Parse(time.DateOnly, p.date)
+		if err != nil {
+			continue
+		}
+		days = append(days, int(date.Unix()/86400))
+		located = append(located, p)
+	}
+	points = located
+	projectPoints(points)
+
+	pairs, err := nearPairs(points, spaceBand, bands)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	observed := knoxTable(pairs, days, timeBand, bands)
+
+	// permuting the dates keeps both the spatial and the temporal
+	// distribution while breaking any link between them
+	expected := make([][]float64, bands)
+	exceeded := make([][]int, bands)
+	for s := range expected {
+		expected[s] = make([]float64, bands)
+		exceeded[s] = make([]int, bands)
+	}
+	shuffled := append([]int(nil), days...)
+	for range iterations {
+		rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		simulated := knoxTable(pairs, shuffled, timeBand, bands)
+		for s := range simulated {
+			for t, count := range simulated[s] {
+				expected[s][t] += float64(count)
+				if count >= observed[s][t] {
+					exceeded[s][t]++
+				}
+			}
+		}
+	}
+
+	table := make([]NearRepeatBand, 0, bands*bands)
+	for s := range bands {
+		for t := range bands {
+			band := NearRepeatBand{
+				DistanceFrom: float64(s) * spaceBand,
+				DistanceTo:   float64(s+1) * spaceBand,
+				DaysFrom:     t * timeBand,
+				DaysTo:       (t+1)*timeBand - 1,
+				Observed:     observed[s][t],
+				Expected:     expected[s][t] / float64(iterations),
+				PValue:       float64(exceeded[s][t]+1) / float64(iterations+1),
+			}
+			if band.Expected > 0 {
+				ratio := float64(band.Observed) / band.Expected
+				band.Ratio = &ratio
+			}
+			table = append(table, band)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bands":           table,
+		"space_band_m":    spaceBand,
+		"time_band_days":  timeBand,
+		"iterations":      iterations,
+		"total_incidents": len(points),
+		"total_pairs":     len(pairs),
+		"year":            filters.Years,
+		"crime_type":      filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,358 @@
+package public
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type NeighborhoodRank struct {
+	ByCount     int  `json:"by_count"`
+	ByPerCapita *int `json:"by_per_capita"`
+	ByPerArea   *int `json:"by_per_area"`
+	Of          int  `json:"of"`
+}
+
+type NeighborRate struct {
+	AreaRate
+	DistanceM float64 `json:"distance_m"`
+}
+
+type NeighborhoodProfile struct {
+	Name       string           `json:"name"`
+	City       string           `json:"city"`
+	Rate       AreaRate         `json:"rate"`
+	Years      []OrderedPair    `json:"years"`
+	Categories []OrderedPair    `json:"categories"`
+	HourMatrix HourMatrix       `json:"hour_matrix"`
+	Rank       NeighborhoodRank `json:"rank"`
+	Neighbors  []NeighborRate   `json:"neighbors"`
+	Streets    []OrderedPair    `json:"top_streets"`
+	Recent     []CrimeDump      `json:"recent"`
+	id, cityID int64
+}
+
+// Block numbers dropped so "2300 S 72ND ST" and "2400 S 72ND ST" are one street
+const streetName = `regexp_replace(a.street_address, '^[0-9X]+\s+(BLOCK\s+(OF\s+)?)?', '', 'i')`
+
+// Rows of key, count in query order
+func (h *Handler) queryPairs(query string, args []any) ([]OrderedPair, error) {
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := []OrderedPair{}
+	for rows.Next() {
+		var pair OrderedPair
+		if err := rows.Scan(&pair.Key, &pair.Value); err != nil {
+			log.Printf("Error scanning pair: %v", err)
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// Looks up a neighborhood by name, ignoring case. city, when set, picks
+// between neighborhoods of the same name in different cities, otherwise the
+// first city alphabetically wins. found is false when there is none.
+func (h *Handler) getNeighborhood(name, city string) (profile NeighborhoodProfile, found bool, err error) {
+	query := `
+		SELECT n.neighborhood_id, n.neighborhood_name, c.city_id, c.city_name, n.population, n.area_sq_mi::float8
+		FROM neighborhoods n
+		JOIN cities c ON n.city_id = c.city_id
+		WHERE LOWER(n.neighborhood_name) = LOWER($1)
+		AND ($2 = '' OR LOWER(c.city_name) = LOWER($2))
+		ORDER BY c.city_name
+		LIMIT 1
+	`
+	err = h.pool.QueryRow(context.Background(), query, name, city).Scan(
+		&profile.id, &profile.Name, &profile.cityID, &profile.City,
+		&profile.Rate.Population, &profile.Rate.AreaSqMi,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, false, nil
+	}
+	if err != nil {
+		log.Printf("Error looking up neighborhood %s: %v", name, err)
+		return profile, false, err
+	}
+	profile.Rate.Name = profile.Name
+	return profile, true, nil
+}
+
+// 1-based position of name in rates sorted by key, largest first. nil when the
+// neighborhood has no value for key.
+func rankBy(rates []AreaRate, name string, key func(AreaRate) *float64) *int {
+	ranked := make([]AreaRate, 0, len(rates))
+	for _, rate := range rates {
+		if key(rate) != nil {
+			ranked = append(ranked, rate)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return *key(ranked[i]) > *key(ranked[j])
+	})
+	for i, rate := range ranked {
+		if rate.Name == name {
+			rank := i + 1
+			return &rank
+		}
+	}
+	return nil
+}
+
+// Every neighborhood in a city with its denominators and no incidents
+func (h *Handler) getCityNeighborhoods(cityID int64) ([]AreaRate, error) {
+	query := `
+		SELECT neighborhood_name, population, area_sq_mi::float8
+		FROM neighborhoods
+		WHERE city_id = $1
+		ORDER BY neighborhood_name
+	`
+	rows, err := h.pool.Query(context.Background(), query, cityID)
+	if err != nil {
+		log.Printf("Error querying neighborhoods of city %d: %v", cityID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	neighborhoods := []AreaRate{}
+	for rows.Next() {
+		var rate AreaRate
+		if err := rows.Scan(&rate.Name, &rate.Population, &rate.AreaSqMi); err != nil {
+			log.Printf("Error scanning neighborhood: %v", err)
+			continue
+		}
+		zero := 0.0
+		if rate.Population != nil && *rate.Population > 0 {
+			rate.Per1000 = &zero
+		}
+		if rate.AreaSqMi != nil && *rate.AreaSqMi > 0 {
+			rate.PerSqMi = &zero
+		}
+		neighborhoods = append(neighborhoods, rate)
+	}
+	return neighborhoods, nil
+}
+
+// Ranks the neighborhood against every other in its city, those without
+// incidents in the period included, and fills in its own rate. Returns the
+// peer rates by name for the neighbor lookup.
+func (h *Handler) rankNeighborhood(filters CrimeFilters, profile *NeighborhoodProfile) (map[string]AreaRate, error) {
+	peers := filters
+	peers.Cities = nil
+	peers.CityID = profile.cityID
+	peers.Neighborhoods = nil
+	peers.NeighborhoodID = 0
+	rates, _, err := h.getAreaRates(peers, "neighborhood")
+	if err != nil {
+		return nil, err
+	}
+	all, err := h.getCityNeighborhoods(profile.cityID)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]AreaRate, len(all))
+	for _, rate := range rates {
+		byName[rate.Name] = rate
+	}
+	for _, rate := range all {
+		if _, ok := byName[rate.Name]; !ok {
+			rates = append(rates, rate)
+			byName[rate.Name] = rate
+		}
+	}
+	if rate, ok := byName[profile.Name]; ok {
+		profile.Rate = rate
+	}
+
+	count := func(r AreaRate) *float64 {
+		count := float64(r.Count)
+		return &count
+	}
+	profile.Rank = NeighborhoodRank{
+		ByCount:     *rankBy(rates, profile.Name, count),
+		ByPerCapita: rankBy(rates, profile.Name, func(r AreaRate) *float64 { return r.Per1000 }),
+		ByPerArea:   rankBy(rates, profile.Name, func(r AreaRate) *float64 { return r.PerSqMi }),
+		Of:          len(rates),
+	}
+	return byName, nil
+}
+
+// Nearest neighborhoods in the same city by the distance between the centers
+// of their geocoded addresses, from sql/neighborhood_centers.sql
+func (h *Handler) getNeighborRates(profile NeighborhoodProfile, rates map[string]AreaRate, k int) ([]NeighborRate, error) {
+	query := `
+		SELECT n.neighborhood_name, ST_Distance(t.center, o.center) as distance_m
+		FROM neighborhood_centers t
+		JOIN neighborhood_centers o ON o.neighborhood_id <> t.neighborhood_id
+		JOIN neighborhoods n ON o.neighborhood_id = n.neighborhood_id
+		WHERE t.neighborhood_id = $1 AND n.city_id = $2
+		ORDER BY distance_m
+		LIMIT $3
+	`
+	rows, err := h.pool.Query(context.Background(), query, profile.id, profile.cityID, k)
+	if err != nil {
+		log.Printf("Error querying neighbors of %s: %v", profile.Name, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	neighbors := []NeighborRate{}
+	for rows.Next() {
+		var neighbor NeighborRate
+		if err := rows.Scan(&neighbor.Name, &neighbor.DistanceM); err != nil {
+			log.Printf("Error scanning neighbor: %v", err)
+			continue
+		}
+		if rate, ok := rates[neighbor.Name]; ok {
+			neighbor.AreaRate = rate
+		}
+		neighbors = append(neighbors, neighbor)
+	}
+	return neighbors, nil
+}
+
+func (h *Handler) getNeighborhoodProfile(filters CrimeFilters, profile NeighborhoodProfile, neighbors, streets, recent int) (NeighborhoodProfile, error) {
+	filters.Neighborhoods = nil
+	filters.NeighborhoodID = profile.id
+
+	// every year on record, the other filters still apply
+	allYears := filters
+	allYears.Years = nil
+	allYears.StartDate = ""
+	allYears.EndDate = ""
+	query, args := allYears.appendWhere(`
+		SELECT EXTRACT(YEAR FROM ci.incident_date)::int::text as year, COUNT(*) as count
+		`+crimeDumpFrom, []any{})
+	years, err := h.queryPairs(query+" GROUP BY year ORDER BY year", args)
+	if err != nil {
+		log.Printf("Error querying yearly counts for %s: %v", profile.Name, err)
+		return profile, err
+	}
+	profile.Years = []OrderedPair{}
+	if len(years) > 0 {
+		first, _ := strconv.Atoi(years[0].Key)
+		last, _ := strconv.Atoi(years[len(years)-1].Key)
+		counts := make(map[string]int, len(years))
+		for _, year := range years {
+			counts[year.Key] = year.Value
+		}
+		for y := first; y <= last; y++ {
+			profile.Years = append(profile.Years, OrderedPair{Key: strconv.Itoa(y), Value: counts[strconv.Itoa(y)]})
+		}
+	}
+
+	query, args = filters.appendWhere(`
+		SELECT COALESCE(cc.category_name, 'Other') as category_name, COUNT(*) as count
+		`+crimeDumpFrom, []any{})
+	profile.Categories, err = h.queryPairs(query+" GROUP BY category_name ORDER BY count DESC, category_name", args)
+	if err != nil {
+		log.Printf("Error querying categories for %s: %v", profile.Name, err)
+		return profile, err
+	}
+
+	profile.HourMatrix, err = h.getHourMatrix(filters)
+	if err != nil {
+		return profile, err
+	}
+
+	rates, err := h.rankNeighborhood(filters, &profile)
+	if err != nil {
+		return profile, err
+	}
+	profile.Neighbors, err = h.getNeighborRates(profile, rates, neighbors)
+	if err != nil {
+		return profile, err
+	}
+
+	query, args = filters.appendWhere(`
+		SELECT `+streetName+` as street, COUNT(*) as count
+		`+crimeDumpFrom+`
+		AND a.street_address IS NOT NULL
+	`, []any{})
+	args = append(args, streets)
+	query += fmt.Sprintf(" GROUP BY street ORDER BY count DESC, street LIMIT $%d", len(args))
+	profile.Streets, err = h.queryPairs(query, args)
+	if err != nil {
+		log.Printf("Error querying streets for %s: %v", profile.Name, err)
+		return profile, err
+	}
+
+	query, args = filters.appendWhere(crimeDumpQuery, []any{})
+	args = append(args, recent)
+	query += fmt.Sprintf(" ORDER BY ci.incident_date DESC, ci.incident_time DESC NULLS LAST LIMIT $%d", len(args))
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying recent crimes for %s: %v", profile.Name, err)
+		return profile, err
+	}
+	defer rows.Close()
+
+	profile.Recent = []CrimeDump{}
+	for rows.Next() {
+		crime, err := scanCrimeDump(rows)
+		if err != nil {
+			log.Printf("Error scanning crime row: %v", err)
+			continue
+		}
+		profile.Recent = append(profile.Recent, crime)
+	}
+	return profile, nil
+}
+
+// Everything about one neighborhood: counts for every year on record,
+// categories, the day of week by hour matrix, rank among the other
+// neighborhoods in its city, the nearest neighborhoods' rates, the streets
+// with the most incidents and the latest incidents. Takes the usual crime
+// filters, which apply to all but the yearly series' dates, and city also picks
+// between neighborhoods of the same name. neighbors, streets and recent set how
+// many of each are returned.
+func (h *Handler) GetNeighborhoodProfile(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	neighbors := parseLimit(c.Query("neighbors"), 5, 20)
+	streets := parseLimit(c.Query("streets"), 10, 50)
+	recent := parseLimit(c.Query("recent"), 10, 100)
+
+	profile, found, err := h.getNeighborhood(c.Param("name"), c.Query("city"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve neighborhood",
+		})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Neighborhood not found"})
+		return
+	}
+
+	profile, err = h.getNeighborhoodProfile(filters, profile, neighbors, streets, recent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build neighborhood profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"neighborhood": profile,
+		"year":         filters.Years,
+		"crime_type":   filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,180 @@
+package public
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const POI_MAX_RADIUS_M = 1000
+
+type POICrimeStats struct {
+	ID         int64         `json:"id"`
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	Latitude   float64       `json:"latitude"`
+	Longitude  float64       `json:"longitude"`
+	Count      int           `json:"count"`
+	Categories []OrderedPair `json:"categories"`
+}
+
+// Incident counts within radius meters of each point of interest, ranked.
+// poi_type limits the POIs (transit, parking, ...). campus=slug or lat/lng with
+// within (meters) limits them to an area, e.g. the bus stops near campus.
+// Takes the usual crime filters.
+func (h *Handler) GetCrimesNearPOIs(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "100"), 64)
+	if err != nil || radius <= 0 || radius > POI_MAX_RADIUS_M {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("radius must be between 0 and %d meters", POI_MAX_RADIUS_M)})
+		return
+	}
+
+	within, err := strconv.ParseFloat(c.DefaultQuery("within", "800"), 64)
+	if err != nil || within < 0 || within > CAMPUS_MAX_BUFFER_M {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("within must be between 0 and %d meters", CAMPUS_MAX_BUFFER_M)})
+		return
+	}
+
+	limit := parseLimit(c.Query("limit"), 20, 200)
+
+	// POIs to rank, $1 is the radius
+	poiQuery := "SELECT poi_id, poi_name, poi_type, latitude::float8, longitude::float8, geog FROM pois WHERE 1=1"
+	args := []any{radius}
+
+	if types := splitParam(c.Query("poi_type")); len(types) > 0 {
+		placeholders := make([]string, len(types))
+		for i, t := range types {
+			args = append(args, strings.ToLower(t))
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		poiQuery += fmt.Sprintf(" AND LOWER(poi_type) IN (%s)", strings.Join(placeholders, ","))
+	}
+
+	if slug := c.Query("campus"); slug != "" {
+		campuses, err := h.getCampuses(slug)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve campus",
+			})
+			return
+		}
+		if len(campuses) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Campus not found"})
+			return
+		}
+		args = append(args, campuses[0].id, within)
+		poiQuery += fmt.Sprintf(" AND ST_DWithin(geog, (SELECT boundary FROM campuses WHERE campus_id = $%d), $%d)", len(args)-1, len(args))
+	} else if c.Query("lat") != "" || c.Query("lng") != "" {
+		lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+		lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+		if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude or longitude"})
+			return
+		}
+		args = append(args, lng, lat, within)
+		poiQuery += fmt.Sprintf(" AND ST_DWithin(geog, ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography, $%d)", len(args)-2, len(args)-1, len(args))
+	}
+
+	// every POI is ranked, those without incidents nearby count zero
+	hits := `
+			SELECT ci.incident_id, COALESCE(cc.category_name, 'Other') as category_name
+			FROM locations l
+			JOIN addresses a ON a.location_id = l.location_id
+			JOIN crime_incidents_partition ci ON ci.address_id = a.address_id
+			JOIN cities c ON a.city_id = c.city_id
+			LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
+			LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
+			LEFT JOIN data_sources s ON ci.source_id = s.source_id
+			WHERE ST_DWithin(p.geog, l.geog, $1)
+		`
+	hits, args = filters.appendWhere(hits, args)
+	query := `
+		WITH p AS (` + poiQuery + `)
+		SELECT
+			p.poi_id,
+			p.poi_name,
+			p.poi_type,
+			p.latitude,
+			p.longitude,
+			hits.category_name,
+			COUNT(hits.incident_id) as count
+		FROM p
+		LEFT JOIN LATERAL (` + hits + `) hits ON true
+		GROUP BY p.poi_id, p.poi_name, p.poi_type, p.latitude, p.longitude, hits.category_name
+	`
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying crimes near POIs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to rank points of interest",
+		})
+		return
+	}
+	defer rows.Close()
+
+	stats := make(map[int64]*POICrimeStats)
+	categories := make(map[int64]map[string]int)
+	for rows.Next() {
+		var poi POICrimeStats
+		var category *string
+		var count int
+		if err := rows.Scan(&poi.ID, &poi.Name, &poi.Type, &poi.Latitude, &poi.Longitude, &category, &count); err != nil {
+			log.Printf("Error scanning POI crime stats: %v", err)
+			continue
+		}
+		if _, ok := stats[poi.ID]; !ok {
+			stats[poi.ID] = &poi
+			categories[poi.ID] = make(map[string]int)
+		}
+		if category != nil {
+			stats[poi.ID].Count += count
+			categories[poi.ID][*category] += count
+		}
+	}
+
+	ranked := make([]POICrimeStats, 0, len(stats))
+	for id, poi := range stats {
+		poi.Categories = sortedPairs(categories[id])
+		ranked = append(ranked, *poi)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+
+	withCrimes := 0
+	for _, poi := range ranked {
+		if poi.Count > 0 {
+			withCrimes++
+		}
+	}
+
+	total := len(ranked)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pois":             ranked,
+		"radius_m":         radius,
+		"total_pois":       total,
+		"pois_with_crimes": withCrimes,
+		"year":             filters.Years,
+		"crime_type":       filters.CrimeTypes,
+	})
+}
@@ -16,17 +16,22 @@ import (
 )
 
 const (
-	EIGHT_HOUR  = 3600 * 8
-	MILE_APPROX = 69
+	EIGHT_HOUR      = 3600 * 8
+	MILE_APPROX     = 69
+	METERS_PER_MILE = 1609.344
+	// mean earth radius
+	EARTH_RADIUS_MILES = 3958.7613
 )
 
 type Handler struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	tiles *tileCache
 }
 
 func NewHandler(pool *pgxpool.Pool) *Handler {
 	return &Handler{
-		pool: pool,
+		pool:  pool,
+		tiles: newTileCache(),
 	}
 }
 
@@ -59,6 +64,7 @@ type CrimeDump struct {
 	Date          string  `json:"date"`
 	Time          string  `json:"time"`
 	Source        string  `json:"source"`
+	Resolved      bool    `json:"resolved"`
 }
 
 type CrimeWithDistance struct {
@@ -73,6 +79,7 @@ type CrimeWithDistance struct {
 	Date          string  `json:"date"`
 	Time          string  `json:"time"`
 	Source        string  `json:"source"`
+	Resolved      bool    `json:"resolved"`
 	Distance      float64 `json:"distance"`
 }
 
@@ -98,6 +105,29 @@ type CrimeStats struct {
 	CrimesByHour  []OrderedPair `json:"crimes_by_hour"`
 	MostDangerous []string      `json:"most_dangerous_areas"`
 	SafestAreas   []string      `json:"safest_areas"`
+	UnknownTime   int           `json:"unknown_time"`
+	FirstDate     string        `json:"first_date"`
+	LastDate      string        `json:"last_date"`
+	Layer         string        `json:"layer"`
+}
+
+// Counts per category (or neighborhood) in one trend period, in the order of
+// CrimeTrends.Categories.
+// Rolling, Expected, Deviation and Anomaly are only set when asked for.
+type TrendBucket struct {
+	Period    string             `json:"period"`
+	Total     int                `json:"total"`
+	Counts    []OrderedPair      `json:"counts"`
+	Rolling   map[string]float64 `json:"rolling,omitempty"`
+	Expected  *float64           `json:"expected,omitempty"`
+	Deviation *float64           `json:"deviation,omitempty"`
+	Anomaly   bool               `json:"anomaly,omitempty"`
+}
+
+type CrimeTrends struct {
+	Buckets     []TrendBucket
+	Categories  []string
+	UnknownTime int
 }
 
 type HeatMapPoint struct {
@@ -403,7 +433,8 @@ func (h *Handler) getDetailedCrimesWithAdvancedFilters(
 			l.longitude as longitude,
 			ci.incident_date::text,
 			ci.incident_time::text,
-			s.source_name
+			s.source_name,
+			ci.is_resolved
 		FROM crime_incidents_` + years[0] + ` ci
 		JOIN data_sources s on ci.source_id = s.source_id
 		JOIN addresses a ON ci.address_id = a.address_id
@@ -426,7 +457,9 @@ func (h *Handler) getDetailedCrimesWithAdvancedFilters(
 			l.latitude as latitude,
     		l.longitude as longitude,
 			ci.incident_date::text,
-			s.source_name
+			ci.incident_time::text,
+			s.source_name,
+			ci.is_resolved
 		FROM (
 		`
 		_, err := sb.WriteString(p1)
@@ -436,13 +469,13 @@ func (h *Handler) getDetailedCrimesWithAdvancedFilters(
 		}
 		var i int
 		for i = 0; i < len(years)-1; i++ {
-			_, err = sb.WriteString("SELECT incident_date, incident_time, address_id, crime_category_id FROM crime_incidents_" + years[i] + "\nUNION ALL\n")
+			_, err = sb.WriteString("SELECT case_num, incident_date, incident_time, address_id, crime_category_id, source_id, is_resolved FROM crime_incidents_" + years[i] + "\nUNION ALL\n")
 			if err != nil {
 				log.Println(err)
 				return nil, err
 			}
 		}
-		_, err = sb.WriteString("SELECT incident_date, incident_time, address_id, crime_category_id FROM crime_incidents_" + years[i])
+		_, err = sb.WriteString("SELECT case_num, incident_date, incident_time, address_id, crime_category_id, source_id, is_resolved FROM crime_incidents_" + years[i])
 		if err != nil {
 			log.Println(err)
 			return nil, err
@@ -566,6 +599,7 @@ func (h *Handler) getDetailedCrimesWithAdvancedFilters(
 			&crime.Date,
 			&crime.Time,
 			&crime.Source,
+			&crime.Resolved,
 		)
 		if err != nil {
 			log.Printf("Error scanning crime row: %v", err)
@@ -578,7 +612,7 @@ func (h *Handler) getDetailedCrimesWithAdvancedFilters(
 
 // Haversine formula to calculate distance between two points in MILES
 func haversineDistanceMiles(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 3959
+	const R = EARTH_RADIUS_MILES
 
 	lat1Rad := lat1 * math.Pi / 180
 	lat2Rad := lat2 * math.Pi / 180
@@ -595,11 +629,9 @@ func haversineDistanceMiles(lat1, lon1, lat2, lon2 float64) float64 {
 
 // Geographic radius filtering - crimes within X miles of a point
 func (h *Handler) GetCrimesInRadius(c *gin.Context) {
-	year := validateYears(c.Query("year"))
 	latStr := c.Query("lat")
 	lonStr := c.Query("lng")
 	radiusStr := c.Query("radius")
-	crimeType := c.Query("type")
 	limitStr := c.Query("limit")
 
 	if latStr == "" || lonStr == "" || radiusStr == "" {
@@ -628,6 +660,12 @@ func (h *Handler) GetCrimesInRadius(c *gin.Context) {
 		return
 	}
 
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	limit := 500
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 2000 {
@@ -635,7 +673,7 @@ func (h *Handler) GetCrimesInRadius(c *gin.Context) {
 		}
 	}
 
-	crimes, err := h.getCrimesInRadius(year, centerLat, centerLon, radius, crimeType, limit)
+	crimes, err := h.getCrimesInRadius(filters, centerLat, centerLon, radius, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to retrieve crimes in radius",
@@ -651,90 +689,23 @@ func (h *Handler) GetCrimesInRadius(c *gin.Context) {
 		"radius_miles": radius,
 		"crimes":       crimes,
 		"count":        len(crimes),
-		"year":         year,
+		"year":         filters.Years,
 	})
 }
 
-func (h *Handler) getCrimesInRadius(year []string, centerLat, centerLon, radius float64, crimeType string, limit int) ([]CrimeWithDistance, error) {
-	var query string
-	if len(year) == 1 {
-		query = `
-		SELECT
-			ci.case_num,
-			cc.category_name,
-			COALESCE(n.neighborhood_name, 'Unknown neighborhood') as neighborhood,
-			COALESCE(a.street_address, 'Unknown Address') as address,
-			c.city_name,
-			a.postal_code,
-			l.latitude as latitude,
-			l.longitude as longitude,
-			ci.incident_date::text,
-			ci.incident_time::text
-		FROM crime_incidents_` + year[0] + ` ci
-		JOIN addresses a ON ci.address_id = a.address_id
-		JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
-		JOIN cities c ON a.city_id = c.city_id
-		LEFT JOIN locations l ON a.location_id = l.location_id
-		LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
-		WHERE l.latitude IS NOT NULL AND l.longitude IS NOT NULL
+// Uses idx_locations_geog, distances are great-circle miles from PostGIS
+func (h *Handler) getCrimesInRadius(filters CrimeFilters, centerLat, centerLon, radius float64, limit int) ([]CrimeWithDistance, error) {
+	query := "SELECT" + crimeDumpColumns + `,
+			ST_Distance(l.geog, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography) as distance_m
+		` + crimeDumpFrom + `
+		AND ST_DWithin(l.geog, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography, $3)
 	`
-	} else {
-		sb := strings.Builder{}
-		p1 := `
-		SELECT 
-			ci.case_num,
-			cc.category_name,
-			COALESCE(n.neighborhood_name, 'Unknown neighborhood') as neighborhood,
-			COALESCE(a.street_address, 'Unknown Address') as address,
-			c.city_name,
-			a.postal_code,
-			l.latitude as latitude,
-			l.longitude as longitude,
-			ci.incident_date::text,
-			ci.incident_time::text
-		FROM (
-		`
-		_, err := sb.WriteString(p1)
-		if err != nil {
-			log.Println(err)
-			return nil, err
-		}
-		for i := range year {
-			_, err = sb.WriteString(`crime_incidents_` + year[i] + `UNION ALL `)
-			if err != nil {
-				log.Println(err)
-				return nil, err
-			}
-		}
-		p2 := `
-		) as ci
-		JOIN addresses a ON ci.address_id = a.address_id
-		JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
-		JOIN cities c ON a.city_id = c.city_id
-		LEFT JOIN locations l ON a.location_id = l.location_id
-		LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
-		WHERE l.latitude IS NOT NULL AND l.longitude IS NOT NULL
-		`
-		_, err = sb.WriteString(p2)
-		if err != nil {
-			log.Println(err)
-			return nil, err
-		}
-		query = sb.String()
-	}
-
-	var args []any
-	argIndex := 1
-
-	if crimeType != "" {
-		query += fmt.Sprintf(" AND LOWER(cc.category_name) = LOWER($%d)", argIndex)
-		args = append(args, crimeType)
-		argIndex++
-	}
+	args := []any{centerLon, centerLat, radius * METERS_PER_MILE}
+	query, args = filters.appendWhere(query, args)
 
 	query += " ORDER BY ci.incident_date DESC"
-	query += fmt.Sprintf(" LIMIT $%d", argIndex)
-	args = append(args, limit*2)
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
 
 	rows, err := h.pool.Query(context.Background(), query, args...)
 	if err != nil {
@@ -743,44 +714,41 @@ func (h *Handler) getCrimesInRadius(year []string, centerLat, centerLon, radius
 	}
 	defer rows.Close()
 
-	var crimesInRadius []CrimeWithDistance
+	crimesInRadius := []CrimeWithDistance{}
 	for rows.Next() {
-		var crime CrimeWithDistance
-		err := rows.Scan(
-			&crime.Case,
-			&crime.CrimeCategory,
-			&crime.Neighborhood,
-			&crime.Street,
-			&crime.City,
-			&crime.Zip,
-			&crime.Latitude,
-			&crime.Longitude,
-			&crime.Date,
-			&crime.Time,
-		)
+		var distanceMeters float64
+		crime, err := scanCrimeDump(rows, &distanceMeters)
 		if err != nil {
 			log.Printf("Error scanning crime row: %v", err)
 			continue
 		}
-
-		distance := haversineDistanceMiles(centerLat, centerLon, crime.Latitude, crime.Longitude)
-		if distance <= radius {
-			crime.Distance = distance
-			crimesInRadius = append(crimesInRadius, crime)
-			if len(crimesInRadius) >= limit {
-				break
-			}
-		}
+		crimesInRadius = append(crimesInRadius, withDistance(crime, distanceMeters/METERS_PER_MILE))
 	}
 
 	return crimesInRadius, nil
 }
 
-// Crime statistics endpoint
+// Crime statistics endpoint. Takes the /crimes/details filters, so any set of
+// years or startDate/endDate span. top_types, top_dates and top_areas limit the
+// ranked lists, hours are always all 24 with unknown times counted apart.
 func (h *Handler) GetCrimeStats(c *gin.Context) {
-	year := validateYear(c.Query("year"))
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	layer, ok := h.layerParam(c)
+	if !ok {
+		return
+	}
 
-	stats, err := h.calculateCrimeStats(year)
+	limits := statsLimits{
+		types: parseLimit(c.Query("top_types"), 50, 200),
+		dates: parseLimit(c.Query("top_dates"), 30, 1000),
+		areas: parseLimit(c.Query("top_areas"), 5, 50),
+	}
+
+	stats, err := h.calculateCrimeStats(filters, layer, limits)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to calculate crime statistics",
@@ -791,136 +759,155 @@ func (h *Handler) GetCrimeStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-func (h *Handler) calculateCrimeStats(year string) (CrimeStats, error) {
+type statsLimits struct {
+	types, dates, areas int
+}
+
+func (h *Handler) calculateCrimeStats(filters CrimeFilters, layer string, limits statsLimits) (CrimeStats, error) {
+	var args []any
+	areaJoin, areaName := "", "COALESCE(n.neighborhood_name, 'Unknown Area')"
+	if layer != NEIGHBORHOOD_LAYER {
+		args = append(args, layer)
+		areaJoin, areaName = areaLayerSQL(layer, 1)
+	}
+
+	base := `
+			SELECT
+				COALESCE(cc.category_name, 'Other') as category,
+				ci.incident_date,
+				ci.incident_time,
+				` + areaName + ` as area
+			FROM crime_incidents_partition ci
+			JOIN addresses a ON ci.address_id = a.address_id
+			JOIN cities c ON a.city_id = c.city_id
+			LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
+			LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
+			LEFT JOIN data_sources s ON ci.source_id = s.source_id` + areaJoin + `
+			WHERE 1=1
+	`
+	base, args = filters.appendWhere(base, args)
+	args = append(args, limits.types, limits.dates)
+	typesLimit, datesLimit := len(args)-1, len(args)
+
 	query := `
-		WITH crime_type_stats AS (
-			SELECT 
+		WITH base AS (` + base + `),
+		crime_type_stats AS (
+			SELECT
 				'type' as stat_type,
-				COALESCE(cc.category_name, 'Other') as key,
+				category as key,
 				COUNT(*) as count,
-				ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC) as rank
-			FROM crime_incidents_` + year + ` ci
-			LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
-			GROUP BY cc.category_name
+				ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC, category) as rank
+			FROM base
+			GROUP BY category
 		),
 		date_stats AS (
-			SELECT 
+			SELECT
 				'date' as stat_type,
-				ci.incident_date::text as key,
+				incident_date::text as key,
 				COUNT(*) as count,
-				ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC) as rank
-			FROM crime_incidents_` + year + ` ci
-			GROUP BY ci.incident_date
+				ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC, incident_date) as rank
+			FROM base
+			GROUP BY incident_date
 		),
 		hour_stats AS (
-			SELECT 
+			SELECT
 				'hour' as stat_type,
-				LPAD(EXTRACT(HOUR FROM COALESCE(ci.incident_time, '00:00:00')::time)::text, 2, '0') as key,
+				LPAD(EXTRACT(HOUR FROM incident_time)::int::text, 2, '0') as key,
 				COUNT(*) as count,
-				EXTRACT(HOUR FROM COALESCE(ci.incident_time, '00:00:00')::time) as sort_order
-			FROM crime_incidents_` + year + ` ci
-			GROUP BY EXTRACT(HOUR FROM COALESCE(ci.incident_time, '00:00:00')::time)
+				EXTRACT(HOUR FROM incident_time)::bigint as rank
+			FROM base
+			WHERE incident_time IS NOT NULL
+			GROUP BY 2, 4
 		),
 		area_stats AS (
-			SELECT 
+			SELECT
 				'area' as stat_type,
-				COALESCE(n.neighborhood_name, 'Unknown Area') as key,
+				area as key,
 				COUNT(*) as count,
-				ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC) as rank
-			FROM crime_incidents_` + year + ` ci
-			JOIN addresses a ON ci.address_id = a.address_id
-			LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
-			GROUP BY n.neighborhood_name
-			HAVING COUNT(*) > 0
+				ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC, area) as rank
+			FROM base
+			GROUP BY area
 		),
-		total_crimes AS (
-			SELECT COUNT(*) as total FROM crime_incidents_` + year + `
+		span AS (
+			SELECT
+				COALESCE(MIN(incident_date)::text, '') as first_date,
+				COALESCE(MAX(incident_date)::text, '') as last_date,
+				COUNT(*) as total,
+				COUNT(*) FILTER (WHERE incident_time IS NULL) as unknown_time
+			FROM base
 		)
-		SELECT stat_type, key, count, 
-			   COALESCE(rank, sort_order) as order_val,
-			   (SELECT total FROM total_crimes) as total_count
-		FROM (
-			SELECT stat_type, key, count, rank, NULL::numeric as sort_order FROM crime_type_stats
+		SELECT stats.*, span.* FROM (
+			SELECT stat_type, key, count, rank FROM crime_type_stats WHERE rank <= $` + strconv.Itoa(typesLimit) + `
 			UNION ALL
-			SELECT stat_type, key, count, rank, NULL::numeric as sort_order FROM date_stats WHERE rank <= 30
+			SELECT stat_type, key, count, rank FROM date_stats WHERE rank <= $` + strconv.Itoa(datesLimit) + `
 			UNION ALL
-			SELECT stat_type, key, count, NULL::bigint as rank, sort_order FROM hour_stats
+			SELECT stat_type, key, count, rank FROM hour_stats
 			UNION ALL
-			SELECT stat_type, key, count, rank, NULL::numeric as sort_order FROM area_stats
-		) combined
-		ORDER BY stat_type, order_val
+			SELECT stat_type, key, count, rank FROM area_stats
+		) stats
+		-- the single span row rides along on every stat row, with no rows at
+		-- all the span is empty anyway
+		CROSS JOIN span
+		ORDER BY stat_type, rank
 	`
 
-	var crimesByType []OrderedPair
-	var crimesByDate []OrderedPair
-	var crimesByHour []OrderedPair
-	var mostDangerous, safestAreas []string
-	var totalCrimes int
-	var areas []struct {
-		name  string
-		count int
-	}
-
-	rows, err := h.pool.Query(context.Background(), query)
+	rows, err := h.pool.Query(context.Background(), query, args...)
 	if err != nil {
+		log.Printf("Error querying crime stats: %v", err)
 		return CrimeStats{}, err
 	}
 	defer rows.Close()
 
+	stats := CrimeStats{
+		CrimesByType:  []OrderedPair{},
+		CrimesByDate:  []OrderedPair{},
+		CrimesByHour:  make([]OrderedPair, 24),
+		MostDangerous: []string{},
+		SafestAreas:   []string{},
+		Layer:         layer,
+	}
+	for hour := range stats.CrimesByHour {
+		stats.CrimesByHour[hour] = OrderedPair{Key: fmt.Sprintf("%02d", hour)}
+	}
+	var areas []string
+
 	for rows.Next() {
 		var statType, key string
 		var count int
-		var orderVal *float64
-		var totalCount int
-
-		if err := rows.Scan(&statType, &key, &count, &orderVal, &totalCount); err != nil {
+		var rank int64
+		if err := rows.Scan(&statType, &key, &count, &rank,
+			&stats.FirstDate, &stats.LastDate, &stats.TotalCrimes, &stats.UnknownTime); err != nil {
+			log.Printf("Error scanning crime stats: %v", err)
 			continue
 		}
 
-		totalCrimes = totalCount
-
 		switch statType {
 		case "type":
-			crimesByType = append(crimesByType, OrderedPair{Key: key, Value: count})
+			stats.CrimesByType = append(stats.CrimesByType, OrderedPair{Key: key, Value: count})
 		case "date":
-			crimesByDate = append(crimesByDate, OrderedPair{Key: key, Value: count})
+			stats.CrimesByDate = append(stats.CrimesByDate, OrderedPair{Key: key, Value: count})
 		case "hour":
-			crimesByHour = append(crimesByHour, OrderedPair{Key: key, Value: count})
+			if rank >= 0 && rank < 24 {
+				stats.CrimesByHour[rank].Value = count
+			}
 		case "area":
-			areas = append(areas, struct {
-				name  string
-				count int
-			}{key, count})
+			areas = append(areas, key)
 		}
 	}
 
-	if len(areas) > 0 {
-		maxAreas := min(len(areas), 5)
-		for i := range maxAreas {
-			mostDangerous = append(mostDangerous, areas[i].name)
-		}
-		if len(areas) > 5 {
-			start := len(areas) - 5
-			for i := start; i < len(areas); i++ {
-				safestAreas = append([]string{areas[i].name}, safestAreas...)
-			}
+	// areas come most crimes first, the safest list is least crimes first
+	stats.MostDangerous = areas[:min(len(areas), limits.areas)]
+	if len(areas) > limits.areas {
+		for i := len(areas) - 1; i >= max(len(areas)-limits.areas, limits.areas); i-- {
+			stats.SafestAreas = append(stats.SafestAreas, areas[i])
 		}
 	}
 
-	if len(mostDangerous) == 0 {
-		mostDangerous = []string{"No data available"}
+	if len(stats.MostDangerous) == 0 {
+		stats.MostDangerous = []string{"No data available"}
 	}
-	if len(safestAreas) == 0 {
-		safestAreas = []string{"No data available"}
-	}
-
-	stats := CrimeStats{
-		TotalCrimes:   totalCrimes,
-		CrimesByType:  crimesByType,
-		CrimesByDate:  crimesByDate,
-		CrimesByHour:  crimesByHour,
-		MostDangerous: mostDangerous,
-		SafestAreas:   safestAreas,
+	if len(stats.SafestAreas) == 0 {
+		stats.SafestAreas = []string{"No data available"}
 	}
 
 	return stats, nil
@@ -1013,13 +1000,57 @@ func (h *Handler) generateHeatMapData(year, crimeType string, gridSize float64)
 	return heatPoints, nil
 }
 
-// Crime trends endpoint - crimes over time
+// Crime trends endpoint - crimes over time.
+// period=day|isoweek|month|quarter|dow|hour, daily/weekly/monthly still work.
+// rolling=7,28 adds trailing means over that many periods, baseline=seasonal
+// adds the expected value and flags periods at least anomaly_z above it,
+// counting baseline_cycles seasons from before the requested range.
 func (h *Handler) GetCrimeTrends(c *gin.Context) {
-	year := validateYear(c.Query("year"))
-	crimeType := c.Query("type")
-	period := c.DefaultQuery("period", "daily") // daily, weekly, monthly
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	trends, err := h.calculateCrimeTrends(year, crimeType, period)
+	period, err := parseTrendPeriod(c.DefaultQuery("period", "day"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var windows []int
+	for _, w := range splitParam(c.Query("rolling")) {
+		window, err := strconv.Atoi(w)
+		if err != nil || window < 2 || window > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rolling windows must be between 2 and 365 periods"})
+			return
+		}
+		windows = append(windows, window)
+	}
+
+	baseline := c.Query("baseline")
+	if baseline != "" && baseline != "seasonal" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "baseline must be seasonal"})
+		return
+	}
+	if baseline != "" && !period.calendar {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "baseline needs a calendar period"})
+		return
+	}
+
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("anomaly_z", "2"), 64)
+	if err != nil || threshold <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "anomaly_z must be a positive number"})
+		return
+	}
+
+	cycles, err := strconv.Atoi(c.DefaultQuery("baseline_cycles", "8"))
+	if err != nil || cycles < 3 || cycles > 52 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "baseline_cycles must be between 3 and 52"})
+		return
+	}
+
+	trends, err := h.calculateCrimeTrends(filters, period, "category")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to calculate crime trends",
@@ -1027,65 +1058,144 @@ func (h *Handler) GetCrimeTrends(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"trends":     trends,
-		"year":       year,
-		"crime_type": crimeType,
-		"period":     period,
-	})
-}
+	response := gin.H{
+		"trends":       trends.Buckets,
+		"categories":   trends.Categories,
+		"unknown_time": trends.UnknownTime,
+		"year":         filters.Years,
+		"crime_type":   filters.CrimeTypes,
+		"period":       period.name,
+	}
 
-func (h *Handler) calculateCrimeTrends(year, crimeType, period string) (map[string]map[string]int, error) {
-	var dateFormat string
-	switch period {
-	case "weekly":
-		dateFormat = "YYYY-\"Week\"-WW"
-	case "monthly":
-		dateFormat = "YYYY-MM"
-	default:
-		dateFormat = "YYYY-MM-DD"
+	if len(windows) > 0 {
+		addRollingMeans(trends.Buckets, windows)
+		response["rolling"] = windows
 	}
+	if baseline != "" {
+		// the baseline looks back before the requested range
+		history := trends
+		if historyFilters, ok := period.historyFilters(filters, cycles); ok {
+			history, err = h.calculateCrimeTrends(historyFilters, period, "category")
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to calculate crime trends",
+				})
+				return
+			}
+			response["baseline_from"] = historyFilters.StartDate
+		}
 
-	query := `
-		SELECT 
-			TO_CHAR(ci.incident_date, '` + dateFormat + `') as time_period,
-			COALESCE(cc.category_name, 'Other') as crime_type,
-			COUNT(*) as count
-		FROM crime_incidents_` + year + ` ci
-		LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
-		WHERE 1=1
-	`
+		// requested buckets keep their own totals, a partial first month is
+		// compared as it was asked for
+		requested := make(map[string]int, len(trends.Buckets))
+		for i, bucket := range trends.Buckets {
+			requested[bucket.Period] = i
+		}
+		for i, bucket := range history.Buckets {
+			if j, ok := requested[bucket.Period]; ok {
+				history.Buckets[i].Total = trends.Buckets[j].Total
+			}
+		}
+		addSeasonalBaseline(history.Buckets, period.season, cycles, threshold)
+		for _, bucket := range history.Buckets {
+			if j, ok := requested[bucket.Period]; ok {
+				trends.Buckets[j].Expected = bucket.Expected
+				trends.Buckets[j].Deviation = bucket.Deviation
+				trends.Buckets[j].Anomaly = bucket.Anomaly
+			}
+		}
 
-	var args []interface{}
-	if crimeType != "" {
-		query += " AND LOWER(cc.category_name) = LOWER($1)"
-		args = append(args, crimeType)
+		anomalies := 0
+		for _, bucket := range trends.Buckets {
+			if bucket.Anomaly {
+				anomalies++
+			}
+		}
+		response["baseline"] = baseline
+		response["baseline_cycles"] = cycles
+		response["anomaly_z"] = threshold
+		response["anomalies"] = anomalies
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) calculateCrimeTrends(filters CrimeFilters, period trendPeriod, group string) (CrimeTrends, error) {
+	trends := CrimeTrends{Buckets: []TrendBucket{}, Categories: []string{}}
+
+	// hours come from the incident time, everything else from the date
+	keyColumn := "ci.incident_date::text"
+	if period.name == "hour" {
+		keyColumn = "LPAD(EXTRACT(HOUR FROM ci.incident_time)::int::text, 2, '0')"
 	}
 
-	query += " GROUP BY time_period, cc.category_name ORDER BY time_period, cc.category_name"
+	query := `
+		SELECT
+			` + keyColumn + ` as time_period,
+			` + trendGroups[group] + ` as series,
+			COUNT(*) as count
+		` + crimeDumpFrom
+	query, args := filters.appendWhere(query, []any{})
+	query += " GROUP BY time_period, series"
 
 	rows, err := h.pool.Query(context.Background(), query, args...)
 	if err != nil {
 		log.Printf("Error querying crime trends: %v", err)
-		return make(map[string]map[string]int), err
+		return trends, err
 	}
 	defer rows.Close()
 
-	trends := make(map[string]map[string]int)
+	counts := make(map[string]map[string]int)
+	totals := make(map[string]int)
+	var firstDay time.Time
 
 	for rows.Next() {
-		var timePeriod, crimeTypeResult string
+		var timePeriod *string
+		var series string
 		var count int
 
-		if err := rows.Scan(&timePeriod, &crimeTypeResult, &count); err != nil {
+		if err := rows.Scan(&timePeriod, &series, &count); err != nil {
+			continue
+		}
+		if timePeriod == nil {
+			trends.UnknownTime += count
 			continue
 		}
 
-		if trends[timePeriod] == nil {
-			trends[timePeriod] = make(map[string]int)
+		key := *timePeriod
+		if period.key != nil {
+			day, err := time.Parse(time.DateOnly, key)
+			if err != nil {
+				continue
+			}
+			if firstDay.IsZero() || day.Before(firstDay) {
+				firstDay = day
+			}
+			key = period.key(day)
 		}
-		trends[timePeriod][crimeTypeResult] = count
-		trends[timePeriod]["total"] += count
+
+		if counts[key] == nil {
+			counts[key] = make(map[string]int)
+		}
+		counts[key][series] += count
+		totals[series] += count
+	}
+
+	if firstDay.IsZero() {
+		firstDay = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+
+	for _, pair := range sortedPairs(totals) {
+		trends.Categories = append(trends.Categories, pair.Key)
+	}
+
+	for _, key := range period.buckets(filters, firstDay) {
+		bucket := TrendBucket{Period: key, Counts: make([]OrderedPair, len(trends.Categories))}
+		for i, category := range trends.Categories {
+			bucket.Counts[i] = OrderedPair{Key: category, Value: counts[key][category]}
+			bucket.Total += counts[key][category]
+		}
+		trends.Buckets = append(trends.Buckets, bucket)
 	}
 
 	return trends, nil
@@ -1103,7 +1213,12 @@ func (h *Handler) GetDangerousAreas(c *gin.Context) {
 		}
 	}
 
-	areaStats, err := h.getAreaStatistics(year, includeDetails, limit)
+	layer, ok := h.layerParam(c)
+	if !ok {
+		return
+	}
+
+	areaStats, err := h.getAreaStatistics(year, layer, includeDetails, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to retrieve area statistics",
@@ -1133,25 +1248,31 @@ func (h *Handler) GetDangerousAreas(c *gin.Context) {
 		"safest":          safest,
 		"total_areas":     len(areaStats),
 		"year":            year,
+		"layer":           layer,
 		"include_details": includeDetails,
 	})
 }
 
-func (h *Handler) getAreaStatistics(year string, includeDetails bool, limit int) ([]CrimesByArea, error) {
+func (h *Handler) getAreaStatistics(year, layer string, includeDetails bool, limit int) ([]CrimesByArea, error) {
+	args := []any{limit}
+	if layer != NEIGHBORHOOD_LAYER {
+		args = append(args, layer)
+	}
+	areaJoin, areaName := areaLayerSQL(layer, 2)
+
 	statsQuery := `
 		SELECT 
-			COALESCE(n.neighborhood_name, 'Unknown Area') as area_name,
+			` + areaName + ` as area_name,
 			COUNT(*) as crime_count
 		FROM crime_incidents_` + year + ` ci
-		JOIN addresses a ON ci.address_id = a.address_id
-		LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
-		GROUP BY n.neighborhood_name
+		JOIN addresses a ON ci.address_id = a.address_id` + areaJoin + `
+		GROUP BY 1
 		HAVING COUNT(*) > 0
 		ORDER BY crime_count DESC
 		LIMIT $1
 	`
 
-	rows, err := h.pool.Query(context.Background(), statsQuery, limit)
+	rows, err := h.pool.Query(context.Background(), statsQuery, args...)
 	if err != nil {
 		log.Printf("Error querying area statistics: %v", err)
 		return []CrimesByArea{}, err
@@ -1159,6 +1280,7 @@ func (h *Handler) getAreaStatistics(year string, includeDetails bool, limit int)
 	defer rows.Close()
 
 	var areaStats []CrimesByArea
+	var names []string
 
 	for rows.Next() {
 		var area CrimesByArea
@@ -1169,52 +1291,68 @@ func (h *Handler) getAreaStatistics(year string, includeDetails bool, limit int)
 			continue
 		}
 
-		if includeDetails {
-			crimes, err := h.getCrimesForNeighborhood(area.Area, year, 10)
-			if err != nil {
-				log.Printf("Error getting crimes for area %s: %v", area.Area, err)
-				continue
-			}
-			area.Crimes = crimes
-		}
-
 		areaStats = append(areaStats, area)
+		names = append(names, area.Area)
+	}
+	rows.Close()
+
+	if includeDetails && len(names) > 0 {
+		crimes, err := h.getRecentCrimesByArea(layer, year, names, 10)
+		if err != nil {
+			return areaStats, err
+		}
+		for i := range areaStats {
+			areaStats[i].Crimes = crimes[areaStats[i].Area]
+		}
 	}
 
 	return areaStats, nil
 }
 
-// Gets crimes for certain neighborhood
-func (h *Handler) getCrimesForNeighborhood(neighborhood, year string, limit int) ([]Crime, error) {
+// Latest incidents in each of the areas of a layer, up to perArea each, in one
+// query rather than one per area
+func (h *Handler) getRecentCrimesByArea(layer, year string, areas []string, perArea int) (map[string][]Crime, error) {
+	args := []any{areas, perArea}
+	if layer != NEIGHBORHOOD_LAYER {
+		args = append(args, layer)
+	}
+	areaJoin, areaName := areaLayerSQL(layer, 3)
+
 	query := `
-		SELECT 
-			ci.incident_date::text, 
-			COALESCE(ci.incident_time, '00:00') as incident_time,
-			COALESCE(l.latitude, 47.2529) as latitude,
-			COALESCE(l.longitude, -122.4443) as longitude,
-			COALESCE(a.street_address, 'Unknown Address') as address,
-			COALESCE(cc.category_name, 'Other') as crime_type
-		FROM crime_incidents_` + year + ` ci
-		JOIN addresses a ON ci.address_id = a.address_id
-		LEFT JOIN neighborhoods n ON a.neighborhood_id = n.neighborhood_id
-		LEFT JOIN locations l ON a.location_id = l.location_id
-		LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
-		WHERE COALESCE(n.neighborhood_name, 'Unknown Area') = $1
-		ORDER BY ci.incident_date DESC
-		LIMIT $2
+		SELECT area_name, incident_date, incident_time, latitude, longitude, address, crime_type
+		FROM (
+			SELECT
+				` + areaName + ` as area_name,
+				ci.incident_date::text,
+				COALESCE(ci.incident_time, '00:00') as incident_time,
+				COALESCE(l.latitude, 47.2529) as latitude,
+				COALESCE(l.longitude, -122.4443) as longitude,
+				COALESCE(a.street_address, 'Unknown Address') as address,
+				COALESCE(cc.category_name, 'Other') as crime_type,
+				ROW_NUMBER() OVER (PARTITION BY ` + areaName + ` ORDER BY ci.incident_date DESC) as rn
+			FROM crime_incidents_` + year + ` ci
+			JOIN addresses a ON ci.address_id = a.address_id` + areaJoin + `
+			LEFT JOIN locations l ON a.location_id = l.location_id
+			LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
+			WHERE ` + areaName + ` = ANY($1)
+		) recent
+		WHERE rn <= $2
+		ORDER BY area_name, rn
 	`
 
-	rows, err := h.pool.Query(context.Background(), query, neighborhood, limit)
+	rows, err := h.pool.Query(context.Background(), query, args...)
 	if err != nil {
-		log.Printf("Error querying crimes for area %s: %v", neighborhood, err)
-		return []Crime{}, err
+		log.Printf("Error querying recent crimes by area: %v", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	var crimes []Crime
+	crimes := make(map[string][]Crime)
 	for rows.Next() {
+		var area string
 		var crime Crime
 		err := rows.Scan(
+			&area,
 			&crime.Date,
 			&crime.Time,
 			&crime.Latitude,
@@ -1226,9 +1364,8 @@ func (h *Handler) getCrimesForNeighborhood(neighborhood, year string, limit int)
 			log.Printf("Error scanning crime: %v", err)
 			continue
 		}
-		crimes = append(crimes, crime)
+		crimes[area] = append(crimes[area], crime)
 	}
-
 	return crimes, nil
 }
 
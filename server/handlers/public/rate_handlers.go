@@ -0,0 +1,187 @@
+package public
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Incidents for one city or neighborhood against its population and land area,
+// per year of the filtered span. Rates are left null where the denominator
+// hasn't been loaded.
+type AreaRate struct {
+	Name       string   `json:"name"`
+	Count      int      `json:"count"`
+	Population *int     `json:"population"`
+	AreaSqMi   *float64 `json:"area_sq_mi"`
+	Per1000    *float64 `json:"per_1000_residents"`
+	PerSqMi    *float64 `json:"per_sq_mi"`
+}
+
+// Grouping and denominator columns for each rate level
+var rateLevels = map[string]struct {
+	name, population, area, group string
+}{
+	"city": {
+		name:       "c.city_name",
+		population: "c.population",
+		area:       "c.area_sq_mi::float8",
+		group:      "c.city_id",
+	},
+	"neighborhood": {
+		name:       "n.neighborhood_name",
+		population: "n.population",
+		area:       "n.area_sq_mi::float8",
+		group:      "n.neighborhood_id",
+	},
+}
+
+// Length in years of the days the filters cover, up to today. Open ended
+// filters start at the first incident on record.
+func (h *Handler) filterSpanYears(filters CrimeFilters) (float64, error) {
+	var firstDay time.Time
+	if len(filters.Years) == 0 && filters.StartDate == "" {
+		var first *time.Time
+		err := h.pool.QueryRow(context.Background(), "SELECT MIN(incident_date) FROM crime_incidents_partition").Scan(&first)
+		if err != nil {
+			log.Printf("Error querying first incident date: %v", err)
+			return 0, err
+		}
+		if first == nil {
+			return 0, nil
+		}
+		firstDay = first.UTC()
+	}
+
+	days := 0.0
+	for _, r := range filters.dateRanges(firstDay) {
+		days += r[1].Sub(r[0]).Hours()/24 + 1
+	}
+	return days / 365.25, nil
+}
+
+// Annualized rates, along with the span in years they are averaged over
+func (h *Handler) getAreaRates(filters CrimeFilters, level string) ([]AreaRate, float64, error) {
+	spanYears, err := h.filterSpanYears(filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cols := rateLevels[level]
+	query := `
+		SELECT
+			` + cols.name + ` as name,
+			` + cols.population + ` as population,
+			` + cols.area + ` as area_sq_mi,
+			COUNT(*) as count
+		` + crimeDumpFrom + `
+		AND ` + cols.group + ` IS NOT NULL
+	`
+	query, args := filters.appendWhere(query, []any{})
+	query += " GROUP BY " + cols.group + ", " + cols.name + ", " + cols.population + ", " + cols.area
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying %s rates: %v", level, err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	rates := []AreaRate{}
+	for rows.Next() {
+		var rate AreaRate
+		if err := rows.Scan(&rate.Name, &rate.Population, &rate.AreaSqMi, &rate.Count); err != nil {
+			log.Printf("Error scanning %s rate: %v", level, err)
+			continue
+		}
+		if spanYears > 0 {
+			perYear := float64(rate.Count) / spanYears
+			if rate.Population != nil && *rate.Population > 0 {
+				per1000 := perYear / float64(*rate.Population) * 1000
+				rate.Per1000 = &per1000
+			}
+			if rate.AreaSqMi != nil && *rate.AreaSqMi > 0 {
+				perSqMi := perYear / *rate.AreaSqMi
+				rate.PerSqMi = &perSqMi
+			}
+		}
+		rates = append(rates, rate)
+	}
+	return rates, spanYears, nil
+}
+
+// Crime rates per 1,000 residents and per square mile, per year so spans of
+// several years compare with one.
+// level=city|neighborhood, sort=per_capita|per_area|count. Areas without the
+// denominator being sorted on go last.
+func (h *Handler) GetCrimeRates(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	level := c.DefaultQuery("level", "city")
+	if _, ok := rateLevels[level]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "level must be city or neighborhood"})
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort", "per_capita")
+	var key func(AreaRate) *float64
+	switch sortBy {
+	case "per_capita":
+		key = func(r AreaRate) *float64 { return r.Per1000 }
+	case "per_area":
+		key = func(r AreaRate) *float64 { return r.PerSqMi }
+	case "count":
+		key = func(r AreaRate) *float64 {
+			count := float64(r.Count)
+			return &count
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be per_capita, per_area or count"})
+		return
+	}
+
+	rates, spanYears, err := h.getAreaRates(filters, level)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to calculate crime rates",
+		})
+		return
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		a, b := key(rates[i]), key(rates[j])
+		if a == nil || b == nil {
+			return a != nil
+		}
+		if *a != *b {
+			return *a > *b
+		}
+		return rates[i].Name < rates[j].Name
+	})
+
+	withDenominators := 0
+	for _, rate := range rates {
+		if key(rate) != nil {
+			withDenominators++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rates":       rates,
+		"level":       level,
+		"sort":        sortBy,
+		"total_areas": len(rates),
+		"ranked":      withDenominators,
+		"span_years":  spanYears,
+		"year":        filters.Years,
+		"crime_type":  filters.CrimeTypes,
+	})
+}
@@ -0,0 +1,274 @@
+package public
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SAFETY_RADIUS_MI      = 0.5
+	SAFETY_HALF_DISTANCE  = 200.0 // meters
+	SAFETY_HALF_LIFE_DAYS = 90.0
+	SAFETY_LOOKBACK_DAYS  = 365
+)
+
+// Category severity, matched on the first keyword found in the lowercased
+// category name. Anything unmatched weighs 1.
+var severityWeights = []struct {
+	keyword string
+	weight  float64
+}{
+	{"homicide", 10},
+	{"kidnap", 8},
+	{"sex", 8},
+	{"robbery", 7},
+	{"assault", 6},
+	{"weapon", 5},
+	{"arson", 5},
+	{"burglary", 4},
+	{"motor vehicle theft", 3},
+	{"stolen property", 2},
+	{"larceny", 2},
+	{"theft", 2},
+	{"vandalism", 2},
+	{"drug", 2},
+	{"fraud", 1},
+	{"traffic", 0.5},
+}
+
+// Category severity as a SQL CASE on the lowercased category name, with the
+// keywords and weights appended to args
+func severitySQL(args []any) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString("CASE")
+	for _, s := range severityWeights {
+		args = append(args, "%"+s.keyword+"%", s.weight)
+		fmt.Fprintf(&sb, " WHEN LOWER(cc.category_name) LIKE $%d THEN $%d::float8", len(args)-1, len(args))
+	}
+	sb.WriteString(" ELSE 1 END")
+	return sb.String(), args
+}
+
+// Integral of the distance decay over a disc of radius r meters, in square
+// meters. Spreading the citywide weight over the city with this gives the
+// risk an average point would see.
+func decayArea(r float64) float64 {
+	k := math.Ln2 / SAFETY_HALF_DISTANCE
+	return 2 * math.Pi / (k * k) * (1 - math.Exp(-k*r)*(1+k*r))
+}
+
+type SafetyContribution struct {
+	CrimeWithDistance
+	AgeDays  int     `json:"age_days"`
+	Severity float64 `json:"severity"`
+	Weight   float64 `json:"weight"`
+}
+
+// Severity and recency weighted incident total for a city over the lookback,
+// and its land area in square meters
+func (h *Handler) getCityRiskBaseline(city string, since, today time.Time) (weight, areaM2 float64, err error) {
+	args := []any{city, since.Format(time.DateOnly), today.Format(time.DateOnly), SAFETY_HALF_LIFE_DAYS}
+	severity, args := severitySQL(args)
+	query := `
+		SELECT COALESCE(SUM(
+			` + severity + ` * power(2, -($3::date - ci.incident_date) / $4::float8)
+		), 0)::float8
+		FROM crime_incidents_partition ci
+		JOIN addresses a ON ci.address_id = a.address_id
+		JOIN cities c ON a.city_id = c.city_id
+		LEFT JOIN crime_categories cc ON ci.crime_category_id = cc.crime_category_id
+		WHERE c.city_name = $1 AND ci.incident_date >= $2
+	`
+	if err := h.pool.QueryRow(context.Background(), query, args...).Scan(&weight); err != nil {
+		log.Printf("Error querying safety baseline for %s: %v", city, err)
+		return 0, 0, err
+	}
+
+	// the loaded land area, or the hull of the city's geocoded addresses
+	areaQuery := `
+		SELECT COALESCE(
+			(SELECT MAX(area_sq_mi)::float8 * $2 * $2 FROM cities WHERE city_name = $1),
+			(SELECT ST_Area(ST_ConvexHull(ST_Collect(l.geog::geometry))::geography)
+			 FROM addresses a
+			 JOIN cities c ON a.city_id = c.city_id
+			 JOIN locations l ON a.location_id = l.location_id
+			 WHERE c.city_name = $1),
+			0)
+	`
+	if err := h.pool.QueryRow(context.Background(), areaQuery, city, METERS_PER_MILE).Scan(&areaM2); err != nil {
+		log.Printf("Error querying area of %s: %v", city, err)
+		return 0, 0, err
+	}
+	return weight, areaM2, nil
+}
+
+type safetyRisk struct {
+	risk          float64
+	incidents     int
+	city          string // the city most of the incidents are in
+	contributions []SafetyContribution
+}
+
+// Weighted risk of every incident within radius miles since the lookback
+// start, summed in the database, with the limit largest contributions
+func (h *Handler) getSafetyRisk(lat, lng, radius float64, since, today time.Time, limit int) (safetyRisk, error) {
+	result := safetyRisk{contributions: []SafetyContribution{}}
+
+	args := []any{lng, lat, radius * METERS_PER_MILE, since.Format(time.DateOnly), today.Format(time.DateOnly),
+		SAFETY_HALF_DISTANCE, SAFETY_HALF_LIFE_DAYS}
+	severity, args := severitySQL(args)
+	scored := `
+		WITH nearby AS (
+			SELECT` + crimeDumpColumns + `,
+				ST_Distance(l.geog, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography) as distance_m,
+				GREATEST(0, $5::date - ci.incident_date) as age_days,
+				` + severity + ` as severity
+			` + crimeDumpFrom + `
+			AND ST_DWithin(l.geog, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography, $3)
+			AND ci.incident_date >= $4
+		),
+		scored AS (
+			SELECT *, severity * power(2, -distance_m / $6::float8) * power(2, -age_days / $7::float8) as weight
+			FROM nearby
+		)
+	`
+
+	query := scored + `
+		SELECT city_name, COUNT(*), SUM(weight)::float8
+		FROM scored
+		GROUP BY city_name
+		ORDER BY COUNT(*) DESC, city_name
+	`
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying safety risk: %v", err)
+		return result, err
+	}
+	for rows.Next() {
+		var city string
+		var count int
+		var weight float64
+		if err := rows.Scan(&city, &count, &weight); err != nil {
+			log.Printf("Error scanning safety risk: %v", err)
+			continue
+		}
+		if result.city == "" {
+			result.city = city
+		}
+		result.incidents += count
+		result.risk += weight
+	}
+	rows.Close()
+
+	args = append(args, limit)
+	query = scored + fmt.Sprintf(" SELECT * FROM scored ORDER BY weight DESC LIMIT $%d", len(args))
+	rows, err = h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying safety contributions: %v", err)
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var distanceMeters float64
+		var contribution SafetyContribution
+		crime, err := scanCrimeDump(rows, &distanceMeters, &contribution.AgeDays, &contribution.Severity, &contribution.Weight)
+		if err != nil {
+			log.Printf("Error scanning crime row: %v", err)
+			continue
+		}
+		contribution.CrimeWithDistance = withDistance(crime, distanceMeters/METERS_PER_MILE)
+		result.contributions = append(result.contributions, contribution)
+	}
+	return result, nil
+}
+
+// Safety score for a point, 0 (worst) to 100. Nearby incidents are weighted by
+// category severity, distance and age, then compared with what an average
+// point in the same city sees: 50 is the city average, 100 means nothing
+// nearby. radius is in miles and days is the lookback. score is null when the
+// city has no area to spread its baseline over.
+func (h *Handler) GetSafetyScore(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude or longitude"})
+		return
+	}
+
+	radius := SAFETY_RADIUS_MI
+	if radiusStr := c.Query("radius"); radiusStr != "" {
+		r, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil || r <= 0 || r > 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "radius must be between 0 and 2 miles"})
+			return
+		}
+		radius = r
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(SAFETY_LOOKBACK_DAYS)))
+	if err != nil || days < 30 || days > 5*365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 30 and 1825"})
+		return
+	}
+
+	limit := parseLimit(c.Query("limit"), 10, 50)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -days)
+	nearby, err := h.getSafetyRisk(lat, lng, radius, since, today, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to calculate safety score",
+		})
+		return
+	}
+
+	response := gin.H{
+		"latitude":      lat,
+		"longitude":     lng,
+		"radius_miles":  radius,
+		"days":          days,
+		"incidents":     nearby.incidents,
+		"risk":          nearby.risk,
+		"contributing":  nearby.contributions,
+		"score":         100.0,
+		"city":          nil,
+		"expected_risk": nil,
+		"relative_risk": nil,
+	}
+	if nearby.incidents == 0 {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// compare against the city most of the nearby incidents are in
+	city := nearby.city
+	cityWeight, areaM2, err := h.getCityRiskBaseline(city, since, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to calculate safety score",
+		})
+		return
+	}
+	response["city"] = city
+	response["score"] = nil
+
+	if areaM2 > 0 && cityWeight > 0 {
+		expected := cityWeight * decayArea(radius*METERS_PER_MILE) / areaM2
+		relative := nearby.risk / expected
+		response["expected_risk"] = expected
+		response["relative_risk"] = relative
+		response["score"] = math.Round(1000/(1+relative)) / 10
+	}
+
+	c.JSON(http.StatusOK, response)
+}
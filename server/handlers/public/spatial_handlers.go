@@ -0,0 +1,391 @@
+package public
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Crimes inside a bounding box (map viewport) or a GeoJSON polygon.
+// GET takes bbox=minLng,minLat,maxLng,maxLat or a url encoded polygon,
+// POST takes the polygon as the request body.
+func (h *Handler) GetCrimesWithin(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := parseLimit(c.Query("limit"), 1000, 5000)
+
+	var bbox BoundingBox
+	var polygon MultiPolygon
+
+	if bboxStr := c.Query("bbox"); bboxStr != "" {
+		bbox, err = parseBoundingBox(bboxStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		var raw []byte
+		if polygonStr := c.Query("polygon"); polygonStr != "" {
+			raw = []byte(polygonStr)
+		} else if c.Request.Method == http.MethodPost {
+			raw, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+				return
+			}
+		}
+
+		if len(raw) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "bbox or polygon is required",
+				"example": "/api/public/crimes/within?bbox=-122.45,47.24,-122.43,47.26&year=2025",
+			})
+			return
+		}
+
+		polygon, err = parseGeoJSONPolygon(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		bbox = polygon.Bounds()
+	}
+
+	crimes, err := h.getCrimesWithin(filters, bbox, polygon, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve crimes within area",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bbox":      bbox,
+		"crimes":    crimes,
+		"count":     len(crimes),
+		"truncated": len(crimes) == limit,
+		"year":      filters.Years,
+	})
+}
+
+func (h *Handler) getCrimesWithin(filters CrimeFilters, bbox BoundingBox, polygon MultiPolygon, limit int) ([]CrimeDump, error) {
+	query := crimeDumpQuery
+	var args []any
+	if polygon == nil {
+		args = append(args, bbox.MinLng, bbox.MinLat, bbox.MaxLng, bbox.MaxLat)
+		query += " AND l.geog && ST_MakeEnvelope($1, $2, $3, $4, 4326)::geography"
+	} else {
+		geojson, err := polygon.GeoJSON()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, geojson)
+		query += " AND ST_Covers(ST_GeomFromGeoJSON($1)::geography, l.geog)"
+	}
+	query, args = filters.appendWhere(query, args)
+
+	query += " ORDER BY ci.incident_date DESC, ci.incident_time DESC NULLS LAST"
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying crimes within area: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	crimes := []CrimeDump{}
+	for rows.Next() {
+		crime, err := scanCrimeDump(rows)
+		if err != nil {
+			log.Printf("Error scanning crime row: %v", err)
+			continue
+		}
+		crimes = append(crimes, crime)
+	}
+
+	return crimes, nil
+}
+
+type CrimeOnRoute struct {
+	CrimeDump
+	DistanceAlong float64 `json:"distance_along"`
+	Offset        float64 `json:"offset"`
+}
+
+// Crimes within a buffer (miles) of a walking route, ordered along the route.
+// The route is an encoded polyline or waypoints=lat,lng;lat,lng;...
+func (h *Handler) GetCrimesAlongRoute(c *gin.Context) {
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := parseLimit(c.Query("limit"), 500, 2000)
+
+	var path []Position
+	if polyline := c.Query("polyline"); polyline != "" {
+		path, err = decodePolyline(polyline)
+	} else if waypoints := c.Query("waypoints"); waypoints != "" {
+		path, err = parseWaypoints(waypoints)
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "polyline or waypoints is required",
+			"example": "/api/public/crimes/corridor?waypoints=47.2390,-122.4346;47.2445,-122.4379&buffer=0.1",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(path) < 2 || len(path) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "route needs between 2 and 1000 points"})
+		return
+	}
+
+	buffer := 0.1
+	if bufferStr := c.Query("buffer"); bufferStr != "" {
+		buffer, err = strconv.ParseFloat(bufferStr, 64)
+		if err != nil || buffer <= 0 || buffer > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "buffer must be between 0 and 1 mile"})
+			return
+		}
+	}
+
+	cumulative := pathCumulativeMiles(path)
+	crimes, err := h.getCrimesAlongRoute(filters, path, cumulative, buffer, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve crimes along route",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"route_miles":  cumulative[len(cumulative)-1],
+		"buffer_miles": buffer,
+		"crimes":       crimes,
+		"count":        len(crimes),
+		"year":         filters.Years,
+	})
+}
+
+func (h *Handler) getCrimesAlongRoute(filters CrimeFilters, path []Position, cumulative []float64, buffer float64, limit int) ([]CrimeOnRoute, error) {
+	route, err := lineStringGeoJSON(path)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{route, buffer * METERS_PER_MILE}
+	query := crimeDumpQuery + " AND ST_DWithin(l.geog, ST_GeomFromGeoJSON($1)::geography, $2)"
+	query, args = filters.appendWhere(query, args)
+
+	query += " ORDER BY ci.incident_date DESC"
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying crimes along route: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	crimes := []CrimeOnRoute{}
+	for rows.Next() {
+		crime, err := scanCrimeDump(rows)
+		if err != nil {
+			log.Printf("Error scanning crime row: %v", err)
+			continue
+		}
+
+		along, offset := projectOntoPath(path, cumulative, crime.Longitude, crime.Latitude)
+		crimes = append(crimes, CrimeOnRoute{CrimeDump: crime, DistanceAlong: along, Offset: offset})
+	}
+
+	sort.Slice(crimes, func(i, j int) bool {
+		return crimes[i].DistanceAlong < crimes[j].DistanceAlong
+	})
+
+	return crimes, nil
+}
+
+// The k incidents closest to a point, nearest first.
+// Same filters as /crimes/radius without having to guess a radius.
+func (h *Handler) GetNearestCrimes(c *gin.Context) {
+	latStr := c.Query("lat")
+	lonStr := c.Query("lng")
+
+	if latStr == "" || lonStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "lat and lng parameters are required",
+			"example": "/api/public/crimes/nearest?lat=47.2446&lng=-122.4376&k=25&year=2025",
+		})
+		return
+	}
+
+	centerLat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil || centerLat < -90 || centerLat > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
+		return
+	}
+
+	centerLon, err := strconv.ParseFloat(lonStr, 64)
+	if err != nil || centerLon < -180 || centerLon > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude"})
+		return
+	}
+
+	k := 25
+	if kStr := c.Query("k"); kStr != "" {
+		k, err = strconv.Atoi(kStr)
+		if err != nil || k <= 0 || k > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "k must be between 1 and 500"})
+			return
+		}
+	}
+
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	crimes, err := h.getNearestCrimes(filters, centerLat, centerLon, k)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve nearest crimes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"center": gin.H{
+			"latitude":  centerLat,
+			"longitude": centerLon,
+		},
+		"k":      k,
+		"crimes": crimes,
+		"count":  len(crimes),
+		"year":   filters.Years,
+	})
+}
+
+const (
+	NEAREST_MAX_CANDIDATES = 50000
+	// <-> on geography is the sphere distance, ST_Distance the spheroid, they
+	// differ by well under 1%
+	NEAREST_SPHEROID_MARGIN = 0.99
+)
+
+// Candidate locations to start the nearest search with. Incidents share
+// locations, so k locations usually hold k incidents and twice that leaves
+// room for the spheroid distance to reorder neighbours at the edge of the set.
+// Filters that drop most incidents start wider.
+func nearestCandidates(filters CrimeFilters, k int) int {
+	candidates := 2 * k
+	if len(filters.CrimeTypes) > 0 {
+		candidates *= 4
+	}
+	if len(filters.Years) > 0 || filters.StartDate != "" || filters.EndDate != "" {
+		candidates *= 4
+	}
+	if len(filters.Sources) > 0 {
+		candidates *= 2
+	}
+	return min(candidates, NEAREST_MAX_CANDIDATES)
+}
+
+// KNN ordering on idx_locations_geog runs over locations alone, incidents are
+// joined onto the candidates. The candidate set grows until its k nearest
+// incidents are all closer than its farthest location, so nothing outside it
+// could be nearer.
+func (h *Handler) getNearestCrimes(filters CrimeFilters, centerLat, centerLon float64, k int) ([]CrimeWithDistance, error) {
+	candidates := nearestCandidates(filters, k)
+	for {
+		crimes, reach, exhausted, err := h.queryNearestCrimes(filters, centerLat, centerLon, k, candidates)
+		if err != nil {
+			return nil, err
+		}
+		complete := len(crimes) == k && crimes[k-1].Distance*METERS_PER_MILE <= reach*NEAREST_SPHEROID_MARGIN
+		if complete || exhausted || candidates >= NEAREST_MAX_CANDIDATES {
+			return crimes, nil
+		}
+		candidates = min(candidates*4, NEAREST_MAX_CANDIDATES)
+	}
+}
+
+// Nearest k incidents among the nearest candidates locations. reach is the
+// distance to the farthest candidate in meters, exhausted is set when there
+// were fewer locations than asked for.
+func (h *Handler) queryNearestCrimes(filters CrimeFilters, centerLat, centerLon float64, k, candidates int) (crimes []CrimeWithDistance, reach float64, exhausted bool, err error) {
+	query := `
+		WITH candidates AS (
+			SELECT location_id, ST_Distance(geog, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography) as candidate_m
+			FROM locations
+			ORDER BY geog <-> ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography
+			LIMIT $3
+		)
+		SELECT` + crimeDumpColumns + `,
+			ST_Distance(l.geog, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography) as distance_m,
+			(SELECT MAX(candidate_m) FROM candidates) as reach_m,
+			(SELECT COUNT(*) FROM candidates) as found
+		` + crimeDumpFrom + `
+		AND a.location_id IN (SELECT location_id FROM candidates)
+	`
+	args := []any{centerLon, centerLat, candidates}
+	query, args = filters.appendWhere(query, args)
+
+	args = append(args, k)
+	query += fmt.Sprintf(" ORDER BY distance_m LIMIT $%d", len(args))
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying nearest crimes: %v", err)
+		return nil, 0, false, err
+	}
+	defer rows.Close()
+
+	crimes = []CrimeWithDistance{}
+	for rows.Next() {
+		var distanceMeters float64
+		var found int
+		crime, err := scanCrimeDump(rows, &distanceMeters, &reach, &found)
+		if err != nil {
+			log.Printf("Error scanning crime row: %v", err)
+			continue
+		}
+		exhausted = found < candidates
+		crimes = append(crimes, withDistance(crime, distanceMeters/METERS_PER_MILE))
+	}
+	return crimes, reach, exhausted, nil
+}
+
+func withDistance(crime CrimeDump, distance float64) CrimeWithDistance {
+	return CrimeWithDistance{
+		Case:          crime.Case,
+		CrimeCategory: crime.CrimeCategory,
+		Neighborhood:  crime.Neighborhood,
+		Street:        crime.Street,
+		City:          crime.City,
+		Zip:           crime.Zip,
+		Latitude:      crime.Latitude,
+		Longitude:     crime.Longitude,
+		Date:          crime.Date,
+		Time:          crime.Time,
+		Source:        crime.Source,
+		Resolved:      crime.Resolved,
+		Distance:      distance,
+	}
+}
@@ -0,0 +1,58 @@
+package public
+
+import "math"
+
+// Mean and sample standard deviation
+func meanStdDev(values []float64) (mean, sd float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	for _, v := range values {
+		sd += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sd / float64(len(values)-1))
+}
+
+func twoSidedPValue(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// Two sided test that two Poisson counts share a rate, a seen over exposure ta
+// and b over tb. Conditioned on a+b, b is binomial with p = tb/(ta+tb); the
+// p-value sums every outcome no more likely than b. Large totals use the
+// normal approximation.
+func poissonRateTest(a int, ta float64, b int, tb float64) float64 {
+	n := a + b
+	if n == 0 {
+		return 1
+	}
+	p := tb / (ta + tb)
+
+	if n > 2000 {
+		z := (float64(b) - float64(n)*p) / math.Sqrt(float64(n)*p*(1-p))
+		return twoSidedPValue(z)
+	}
+
+	logPmf := func(k int) float64 {
+		lnN, _ := math.Lgamma(float64(n + 1))
+		lnK, _ := math.Lgamma(float64(k + 1))
+		lnNK, _ := math.Lgamma(float64(n - k + 1))
+		return lnN - lnK - lnNK + float64(k)*math.Log(p) + float64(n-k)*math.Log1p(-p)
+	}
+
+	observed := logPmf(b)
+	total := 0.0
+	for k := 0; k <= n; k++ {
+		if lp := logPmf(k); lp <= observed+1e-7 {
+			total += math.Exp(lp)
+		}
+	}
+	return math.Min(1, total)
+}
@@ -0,0 +1,32 @@
+package public
+
+import "testing"
+
+func TestPoissonRateTest(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         int
+		ta        float64
+		b         int
+		tb        float64
+		low, high float64
+	}{
+		{"no incidents", 0, 30, 0, 30, 1, 1},
+		{"same counts", 10, 30, 10, 30, 1, 1},
+		{"same rate over a longer exposure", 10, 30, 20, 60, 1, 1},
+		{"all in one period", 0, 1, 10, 1, 2.0 / 1024, 2.0 / 1024},
+		{"one sided counts are symmetric", 10, 1, 0, 1, 2.0 / 1024, 2.0 / 1024},
+		{"small change", 12, 1, 15, 1, 0.5, 0.8},
+		{"large totals, no change", 1500, 1, 1500, 1, 1, 1},
+		{"large totals, rise", 1000, 1, 1300, 1, 0, 1e-6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := poissonRateTest(tt.a, tt.ta, tt.b, tt.tb)
+			if p < tt.low-1e-9 || p > tt.high+1e-9 {
+				t.Errorf("p-value %v, want between %v and %v", p, tt.low, tt.high)
+			}
+		})
+	}
+}
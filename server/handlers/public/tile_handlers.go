@@ -0,0 +1,303 @@
+package public
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TILE_SIZE        = 256
+	MVT_EXTENT       = 4096
+	MVT_CELLS        = 64 // aggregation cells per tile side
+	HEAT_RADIUS_PX   = 12
+	WEB_MERCATOR_MAX = 20037508.342789244
+	TILE_CACHE_SIZE  = 2048
+)
+
+type cachedTile struct {
+	data    []byte
+	etag    string
+	expires time.Time
+}
+
+// In memory tile cache so panning back over the same area skips the database
+type tileCache struct {
+	mu    sync.Mutex
+	tiles map[string]cachedTile
+}
+
+func newTileCache() *tileCache {
+	return &tileCache{tiles: make(map[string]cachedTile)}
+}
+
+func (t *tileCache) get(key string) (cachedTile, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tile, ok := t.tiles[key]
+	if !ok || time.Now().After(tile.expires) {
+		return cachedTile{}, false
+	}
+	return tile, true
+}
+
+func (t *tileCache) put(key string, tile cachedTile) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.tiles) >= TILE_CACHE_SIZE {
+		// drop expired tiles first, then whatever map order gives us
+		now := time.Now()
+		for k, v := range t.tiles {
+			if now.After(v.expires) {
+				delete(t.tiles, k)
+			}
+		}
+		for k := range t.tiles {
+			if len(t.tiles) < TILE_CACHE_SIZE {
+				break
+			}
+			delete(t.tiles, k)
+		}
+	}
+	t.tiles[key] = tile
+}
+
+// Web mercator bounds of a tile
+func tileBounds(z, x, y int) (minX, minY, maxX, maxY float64) {
+	size := 2 * WEB_MERCATOR_MAX / math.Exp2(float64(z))
+	minX = -WEB_MERCATOR_MAX + float64(x)*size
+	maxY = WEB_MERCATOR_MAX - float64(y)*size
+	return minX, maxY - size, minX + size, maxY
+}
+
+// Heat map tiles: /tiles/{z}/{x}/{y}.mvt for vector tiles aggregated per
+// tile cell, /tiles/{z}/{x}/{y}.png for a raster heat surface.
+// Takes year, type/crimeType and the other crime filters.
+func (h *Handler) GetTile(c *gin.Context) {
+	yParam, format, found := strings.Cut(c.Param("y"), ".")
+	if !found || (format != "mvt" && format != "png") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tile must end in .mvt or .png"})
+		return
+	}
+
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	y, errY := strconv.Atoi(yParam)
+	if errZ != nil || errX != nil || errY != nil || z < 0 || z > 22 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tile coordinates"})
+		return
+	}
+	if n := 1 << z; x < 0 || x >= n || y < 0 || y >= n {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tile out of range for zoom"})
+		return
+	}
+
+	filters, err := parseCrimeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// past years don't change, the current one does as new data is loaded
+	maxAge := 24 * time.Hour
+	if len(filters.Years) == 0 || strings.Contains(strings.Join(filters.Years, ","), getCurrentYear()) {
+		maxAge = 15 * time.Minute
+	}
+
+	key := c.Request.URL.Path + "?" + c.Request.URL.RawQuery
+	tile, ok := h.tiles.get(key)
+	if !ok {
+		var data []byte
+		if format == "mvt" {
+			data, err = h.renderVectorTile(filters, z, x, y)
+		} else {
+			data, err = h.renderHeatTile(filters, z, x, y)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to render tile",
+			})
+			return
+		}
+
+		sum := sha1.Sum(data)
+		tile = cachedTile{
+			data:    data,
+			etag:    `"` + hex.EncodeToString(sum[:]) + `"`,
+			expires: time.Now().Add(maxAge),
+		}
+		h.tiles.put(key, tile)
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	c.Header("ETag", tile.etag)
+	if c.GetHeader("If-None-Match") == tile.etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	contentType := "image/png"
+	if format == "mvt" {
+		contentType = "application/vnd.mapbox-vector-tile"
+	}
+	c.Data(http.StatusOK, contentType, tile.data)
+}
+
+// One point feature per aggregation cell with its count and top category
+func (h *Handler) renderVectorTile(filters CrimeFilters, z, x, y int) ([]byte, error) {
+	cellSize := 2 * WEB_MERCATOR_MAX / math.Exp2(float64(z)) / MVT_CELLS
+
+	inner := `
+		SELECT
+			ST_Transform(l.geog::geometry, 3857) as geom,
+			COALESCE(cc.category_name, 'Other') as category_name
+		` + crimeDumpFrom + `
+		AND l.geog && ST_Transform(ST_TileEnvelope($1, $2, $3), 4326)::geography
+	`
+	args := []any{z, x, y, cellSize}
+	inner, args = filters.appendWhere(inner, args)
+
+	query := `
+		WITH points AS (` + inner + `),
+		cells AS (
+			SELECT
+				ST_SnapToGrid(ST_Centroid(ST_Collect(geom)), 1) as geom,
+				COUNT(*) as count,
+				MODE() WITHIN GROUP (ORDER BY category_name) as top_category
+			FROM points
+			GROUP BY ST_SnapToGrid(geom, $4)
+		)
+		SELECT ST_AsMVT(tile, 'crimes', ` + strconv.Itoa(MVT_EXTENT) + `, 'geom')
+		FROM (
+			SELECT
+				ST_AsMVTGeom(cells.geom, ST_TileEnvelope($1, $2, $3), ` + strconv.Itoa(MVT_EXTENT) + `, 64, true) as geom,
+				cells.count,
+				cells.top_category
+			FROM cells
+		) tile
+		WHERE tile.geom IS NOT NULL
+	`
+
+	var data []byte
+	if err := h.pool.QueryRow(context.Background(), query, args...).Scan(&data); err != nil {
+		log.Printf("Error building vector tile %d/%d/%d: %v", z, x, y, err)
+		return nil, err
+	}
+	return data, nil
+}
+
+// Gaussian splat of incident counts, read with a margin so tiles join without seams
+func (h *Handler) renderHeatTile(filters CrimeFilters, z, x, y int) ([]byte, error) {
+	minX, _, maxX, maxY := tileBounds(z, x, y)
+	size := maxX - minX
+	margin := size * HEAT_RADIUS_PX / TILE_SIZE
+
+	query := `
+		SELECT
+			ST_X(ST_Transform(l.geog::geometry, 3857)) as x,
+			ST_Y(ST_Transform(l.geog::geometry, 3857)) as y,
+			COUNT(*) as count
+		` + crimeDumpFrom + `
+		AND l.geog && ST_Transform(ST_Expand(ST_TileEnvelope($1, $2, $3), $4), 4326)::geography
+	`
+	args := []any{z, x, y, margin}
+	query, args = filters.appendWhere(query, args)
+	query += " GROUP BY l.location_id, l.geog"
+
+	rows, err := h.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Error querying heat tile %d/%d/%d: %v", z, x, y, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	kernel := make([]float64, (2*HEAT_RADIUS_PX+1)*(2*HEAT_RADIUS_PX+1))
+	sigma := HEAT_RADIUS_PX / 3.0
+	for dy := -HEAT_RADIUS_PX; dy <= HEAT_RADIUS_PX; dy++ {
+		for dx := -HEAT_RADIUS_PX; dx <= HEAT_RADIUS_PX; dx++ {
+			kernel[(dy+HEAT_RADIUS_PX)*(2*HEAT_RADIUS_PX+1)+dx+HEAT_RADIUS_PX] = math.Exp(-float64(dx*dx+dy*dy) / (2 * sigma * sigma))
+		}
+	}
+
+	heat := make([]float64, TILE_SIZE*TILE_SIZE)
+	for rows.Next() {
+		var px, py float64
+		var count int
+		if err := rows.Scan(&px, &py, &count); err != nil {
+			continue
+		}
+
+		cx := int(math.Floor((px - minX) / size * TILE_SIZE))
+		cy := int(math.Floor((maxY - py) / size * TILE_SIZE))
+		for dy := -HEAT_RADIUS_PX; dy <= HEAT_RADIUS_PX; dy++ {
+			ty := cy + dy
+			if ty < 0 || ty >= TILE_SIZE {
+				continue
+			}
+			for dx := -HEAT_RADIUS_PX; dx <= HEAT_RADIUS_PX; dx++ {
+				tx := cx + dx
+				if tx < 0 || tx >= TILE_SIZE {
+					continue
+				}
+				heat[ty*TILE_SIZE+tx] += float64(count) * kernel[(dy+HEAT_RADIUS_PX)*(2*HEAT_RADIUS_PX+1)+dx+HEAT_RADIUS_PX]
+			}
+		}
+	}
+
+	// fixed saturation instead of per tile normalization so neighbouring tiles match
+	img := image.NewNRGBA(image.Rect(0, 0, TILE_SIZE, TILE_SIZE))
+	for i, v := range heat {
+		if v <= 0 {
+			continue
+		}
+		img.SetNRGBA(i%TILE_SIZE, i/TILE_SIZE, heatColor(1-math.Exp(-v/4)))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Transparent blue through red ramp for t in [0, 1]
+func heatColor(t float64) color.NRGBA {
+	stops := []struct {
+		t       float64
+		r, g, b float64
+	}{
+		{0.0, 0, 0, 255},
+		{0.25, 0, 255, 255},
+		{0.5, 0, 255, 0},
+		{0.75, 255, 255, 0},
+		{1.0, 255, 0, 0},
+	}
+
+	for i := 1; i < len(stops); i++ {
+		if t <= stops[i].t {
+			a, b := stops[i-1], stops[i]
+			f := (t - a.t) / (b.t - a.t)
+			return color.NRGBA{
+				R: uint8(a.r + f*(b.r-a.r)),
+				G: uint8(a.g + f*(b.g-a.g)),
+				B: uint8(a.b + f*(b.b-a.b)),
+				A: uint8(255 * math.Min(1, t*1.5)),
+			}
+		}
+	}
+	return color.NRGBA{R: 255, A: 255}
+}
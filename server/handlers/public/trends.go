@@ -0,0 +1,251 @@
+package public
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// How GetCrimeTrends buckets incidents. Calendar periods are keyed off the
+// incident date, dow and hour are cyclic and always return every bucket.
+type trendPeriod struct {
+	name     string
+	calendar bool
+	season   int // buckets per seasonal cycle used for the baseline
+	key      func(day time.Time) string
+}
+
+var trendPeriods = map[string]trendPeriod{
+	"day": {
+		name:     "day",
+		calendar: true,
+		season:   7,
+		key:      func(day time.Time) string { return day.Format(time.DateOnly) },
+	},
+	"isoweek": {
+		name:     "isoweek",
+		calendar: true,
+		// the same week in earlier years, drifting a week after a week 53 as
+		// FORECAST_SEASON does
+		season: 52,
+		key: func(day time.Time) string {
+			year, week := day.ISOWeek()
+			return fmt.Sprintf("%04d-W%02d", year, week)
+		},
+	},
+	"month": {
+		name:     "month",
+		calendar: true,
+		season:   12,
+		key:      func(day time.Time) string { return day.Format("2006-01") },
+	},
+	"quarter": {
+		name:     "quarter",
+		calendar: true,
+		season:   4,
+		key: func(day time.Time) string {
+			return fmt.Sprintf("%04d-Q%d", day.Year(), (int(day.Month())+2)/3)
+		},
+	},
+	"dow": {
+		name: "dow",
+		key:  func(day time.Time) string { return day.Weekday().String() },
+	},
+	"hour": {
+		name: "hour",
+	},
+}
+
+// Columns the forecast can split weekly counts by
+var trendGroups = map[string]string{
+	"category":     "COALESCE(cc.category_name, 'Other')",
+	"neighborhood": "COALESCE(n.neighborhood_name, 'Unknown neighborhood')",
+}
+
+// Older period names the client already sends
+var trendPeriodAliases = map[string]string{
+	"daily":     "day",
+	"weekly":    "isoweek",
+	"week":      "isoweek",
+	"monthly":   "month",
+	"quarterly": "quarter",
+}
+
+func parseTrendPeriod(period string) (trendPeriod, error) {
+	if alias, ok := trendPeriodAliases[period]; ok {
+		period = alias
+	}
+	p, ok := trendPeriods[period]
+	if !ok {
+		return p, fmt.Errorf("period must be one of day, isoweek, month, quarter, dow or hour")
+	}
+	return p, nil
+}
+
+// ISO weekday names, Monday first
+var isoWeekdays = [7]string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// Every bucket key in order, so periods with no incidents still show up as zero
+func (p trendPeriod) buckets(filters CrimeFilters, firstDay time.Time) []string {
+	switch p.name {
+	case "dow":
+		return slices.Clone(isoWeekdays[:])
+	case "hour":
+		hours := make([]string, 24)
+		for i := range hours {
+			hours[i] = fmt.Sprintf("%02d", i)
+		}
+		return hours
+	}
+
+	var keys []string
+	for _, r := range filters.dateRanges(firstDay) {
+		for day := r[0]; !day.After(r[1]); day = day.AddDate(0, 0, 1) {
+			if key := p.key(day); len(keys) == 0 || keys[len(keys)-1] != key {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// Inclusive day ranges covered by the filters, clipped to today. firstDay
+// bounds the range when neither a year nor a start date is set.
+func (f CrimeFilters) dateRanges(firstDay time.Time) [][2]time.Time {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	start, end := firstDay, today
+	if f.StartDate != "" {
+		start, _ = time.Parse(time.DateOnly, f.StartDate)
+	}
+	if f.EndDate != "" {
+		end, _ = time.Parse(time.DateOnly, f.EndDate)
+	}
+	end = minTime(end, today)
+
+	if len(f.Years) == 0 {
+		if start.After(end) {
+			return nil
+		}
+		return [][2]time.Time{{start, end}}
+	}
+
+	years := make([]int, 0, len(f.Years))
+	for _, year := range f.Years {
+		y, _ := strconv.Atoi(year)
+		years = append(years, y)
+	}
+	slices.Sort(years)
+
+	var ranges [][2]time.Time
+	for _, y := range slices.Compact(years) {
+		from := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC)
+		if f.StartDate != "" && from.Before(start) {
+			from = start
+		}
+		to = minTime(to, end)
+		if !from.After(to) {
+			ranges = append(ranges, [2]time.Time{from, to})
+		}
+	}
+	return ranges
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Trailing mean of the totals over each window, set once a full window is available
+func addRollingMeans(buckets []TrendBucket, windows []int) {
+	for _, window := range windows {
+		sum := 0
+		for i := range buckets {
+			sum += buckets[i].Total
+			if i >= window {
+				sum -= buckets[i-window].Total
+			}
+			if i+1 < window {
+				continue
+			}
+			if buckets[i].Rolling == nil {
+				buckets[i].Rolling = make(map[string]float64)
+			}
+			buckets[i].Rolling[strconv.Itoa(window)] = float64(sum) / float64(window)
+		}
+	}
+}
+
+// Filters reaching cycles seasons back from the first requested day and on to
+// the last, so the first buckets have a baseline too. ok is false when the
+// filters are open ended and already start at the first incident.
+func (p trendPeriod) historyFilters(f CrimeFilters, cycles int) (history CrimeFilters, ok bool) {
+	if len(f.Years) == 0 && f.StartDate == "" {
+		return f, false
+	}
+	ranges := f.dateRanges(time.Time{})
+	if len(ranges) == 0 {
+		return f, false
+	}
+
+	from, to := ranges[0][0], ranges[len(ranges)-1][1]
+	switch p.name {
+	case "day":
+		from = from.AddDate(0, 0, -p.season*cycles)
+	case "isoweek":
+		monday := from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+		from = monday.AddDate(0, 0, -7*p.season*cycles)
+	case "month":
+		from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(-cycles, 0, 0)
+	case "quarter":
+		quarter := time.Month((int(from.Month())-1)/3*3 + 1)
+		from = time.Date(from.Year(), quarter, 1, 0, 0, 0, 0, time.UTC).AddDate(-cycles, 0, 0)
+	}
+
+	history = f
+	history.Years = nil
+	history.StartDate = from.Format(time.DateOnly)
+	history.EndDate = to.Format(time.DateOnly)
+	return history, true
+}
+
+// Expected total from the same point in the previous cycles, e.g. the last
+// eight Mondays for a Monday. Deviation is a z-score and buckets at or above
+// threshold are flagged. The spread is floored at the Poisson value so quiet
+// series with identical history don't flag every extra incident.
+func addSeasonalBaseline(buckets []TrendBucket, season, cycles int, threshold float64) {
+	for i := range buckets {
+		history := make([]float64, 0, cycles)
+		for k := 1; k <= cycles && i-k*season >= 0; k++ {
+			history = append(history, float64(buckets[i-k*season].Total))
+		}
+		if len(history) < 3 {
+			continue
+		}
+
+		mean, sd := meanStdDev(history)
+		sd = math.Max(sd, math.Sqrt(math.Max(mean, 1)))
+		deviation := (float64(buckets[i].Total) - mean) / sd
+
+		buckets[i].Expected = &mean
+		buckets[i].Deviation = &deviation
+		buckets[i].Anomaly = deviation >= threshold
+	}
+}
+
+// Incident counts by ISO weekday (Monday first) and hour of day. Incidents
+// with no recorded time are counted per weekday in UnknownTime instead of
+// being folded into midnight.
+type HourMatrix struct {
+	Days        [7]string  `json:"days"`
+	Counts      [7][24]int `json:"counts"`
+	DayTotals   [7]int     `json:"day_totals"`
+	HourTotals  [24]int    `json:"hour_totals"`
+	UnknownTime [7]int     `json:"unknown_time"`
+	Total       int        `json:"total"`
+}
@@ -32,9 +32,35 @@ func (r *Router) registerPublicRoutes() {
 	api.GET("/crimes", publicHandler.GetCrimes)
 	api.GET("/crimes/details", publicHandler.GetDetailedCrime)
 	api.GET("/crimes/radius", publicHandler.GetCrimesInRadius) // Geographic filtering
+	api.GET("/crimes/within", publicHandler.GetCrimesWithin)   // bbox or polygon
+	api.POST("/crimes/within", publicHandler.GetCrimesWithin)  // GeoJSON polygon body
 	api.GET("/crimes/stats", publicHandler.GetCrimeStats)      // Statistics
 	api.GET("/crimes/heatmap", publicHandler.GetHeatMapData)   // Heat map data
 	api.GET("/crimes/trends", publicHandler.GetCrimeTrends)    // Time trends
 	api.GET("/crimes/areas", publicHandler.GetDangerousAreas)
+	api.GET("/crimes/corridor", publicHandler.GetCrimesAlongRoute)
+	api.GET("/crimes/nearest", publicHandler.GetNearestCrimes)
+	api.GET("/crimes/hexbin", publicHandler.GetHexBins)
+	api.GET("/crimes/density", publicHandler.GetCrimeDensity)
+	api.GET("/crimes/hotspots", publicHandler.GetHotSpots)
+	api.GET("/crimes/clusters", publicHandler.GetCrimeClusters)
+	api.GET("/crimes/near-repeat", publicHandler.GetNearRepeat)
+	api.GET("/crimes/compare", publicHandler.GetCrimeComparison)
+	api.GET("/crimes/forecast", publicHandler.GetCrimeForecast)
+	api.GET("/crimes/hour-matrix", publicHandler.GetHourMatrix)
+	api.GET("/crimes/rates", publicHandler.GetCrimeRates)
 	api.GET("/options", publicHandler.GetCrimesFilterOptions)
+	api.GET("/tiles/:z/:x/:y", publicHandler.GetTile) // {y}.mvt or {y}.png
+	api.GET("/safety-score", publicHandler.GetSafetyScore)
+	api.GET("/campuses", publicHandler.GetCampuses)
+	api.GET("/campuses/:campus/crimes", publicHandler.GetCampusCrimes)
+	api.GET("/campuses/:campus/rings", publicHandler.GetCampusRings)
+	api.GET("/pois/crimes", publicHandler.GetCrimesNearPOIs)
+	api.GET("/layers", publicHandler.GetAreaLayers)
+	api.GET("/crimes/clearance", publicHandler.GetClearanceStats)
+	api.GET("/crimes/intervention", publicHandler.GetInterventionImpact)
+	api.POST("/crimes/intervention", publicHandler.GetInterventionImpact)
+	api.GET("/crimes/displacement", publicHandler.GetDisplacement)
+	api.POST("/crimes/displacement", publicHandler.GetDisplacement)
+	api.GET("/neighborhoods/:name", publicHandler.GetNeighborhoodProfile)
 }
@@ -0,0 +1,89 @@
+-- Administrative area layers: police sectors, beats, council districts, ...
+-- Each layer is a set of boundary polygons and every geocoded address is
+-- assigned to the area of each layer that covers it. Needs sql/spatial.sql.
+-- Load boundaries with `data_parser areas <layer> <geojson> [name property]`.
+CREATE TABLE IF NOT EXISTS public.area_layers
+(
+    layer_id    bigint GENERATED ALWAYS AS IDENTITY NOT NULL PRIMARY KEY,
+    layer_name  varchar(50)                         NOT NULL UNIQUE,
+    description varchar(255),
+    created_at  timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS public.admin_areas
+(
+    area_id    bigint GENERATED ALWAYS AS IDENTITY NOT NULL PRIMARY KEY,
+    layer_id   bigint                              NOT NULL,
+    area_name  varchar(100)                        NOT NULL,
+    boundary   geography(MultiPolygon, 4326)       NOT NULL,
+    created_at timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (layer_id) REFERENCES public.area_layers (layer_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE (layer_id, area_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_areas_boundary ON admin_areas USING gist (boundary);
+
+CREATE TABLE IF NOT EXISTS public.address_areas
+(
+    address_id bigint NOT NULL,
+    layer_id   bigint NOT NULL,
+    area_id    bigint NOT NULL,
+    PRIMARY KEY (address_id, layer_id),
+    FOREIGN KEY (address_id) REFERENCES public.addresses (address_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (layer_id) REFERENCES public.area_layers (layer_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (area_id) REFERENCES public.admin_areas (area_id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_address_areas_area ON address_areas (area_id);
+
+-- (Re)assigns addresses to areas, for one layer, one address, or everything
+-- when both are null. Where polygons overlap the smallest area wins.
+CREATE OR REPLACE FUNCTION assign_address_areas(
+    p_layer_id bigint DEFAULT NULL,
+    p_address_id bigint DEFAULT NULL
+)
+    RETURNS integer
+    LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    v_count integer;
+BEGIN
+    DELETE
+    FROM address_areas aa
+    WHERE (p_layer_id IS NULL OR aa.layer_id = p_layer_id)
+      AND (p_address_id IS NULL OR aa.address_id = p_address_id);
+
+    INSERT
+    INTO address_areas (address_id, layer_id, area_id)
+    SELECT DISTINCT ON (a.address_id, ar.layer_id) a.address_id, ar.layer_id, ar.area_id
+    FROM addresses a
+             JOIN locations l ON a.location_id = l.location_id
+             JOIN admin_areas ar ON ST_Covers(ar.boundary, l.geog)
+    WHERE (p_layer_id IS NULL OR ar.layer_id = p_layer_id)
+      AND (p_address_id IS NULL OR a.address_id = p_address_id)
+    ORDER BY a.address_id, ar.layer_id, ST_Area(ar.boundary);
+
+    GET DIAGNOSTICS v_count = ROW_COUNT;
+    RETURN v_count;
+END;
+$$;
+
+-- new and moved addresses pick up their areas as they are imported
+CREATE OR REPLACE FUNCTION assign_address_areas_trigger()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    PERFORM assign_address_areas(NULL, NEW.address_id);
+    RETURN NEW;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_assign_address_areas ON addresses;
+CREATE TRIGGER trg_assign_address_areas
+    AFTER INSERT OR UPDATE OF location_id
+    ON addresses
+    FOR EACH ROW
+EXECUTE FUNCTION assign_address_areas_trigger();
@@ -0,0 +1,50 @@
+-- Campus registry for the campus proximity endpoints. Needs sql/spatial.sql.
+-- More campuses are rows, not code: insert a boundary and its entrances.
+CREATE TABLE IF NOT EXISTS public.campuses
+(
+    campus_id   bigint GENERATED ALWAYS AS IDENTITY NOT NULL PRIMARY KEY,
+    campus_name varchar(100)                        NOT NULL UNIQUE,
+    slug        varchar(50)                         NOT NULL UNIQUE,
+    city_id     bigint,
+    boundary    geography(MultiPolygon, 4326)       NOT NULL,
+    created_at  timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (city_id) REFERENCES public.cities (city_id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS public.campus_entrances
+(
+    entrance_id   bigint GENERATED ALWAYS AS IDENTITY NOT NULL PRIMARY KEY,
+    campus_id     bigint                              NOT NULL,
+    entrance_name varchar(100)                        NOT NULL,
+    geog          geography(Point, 4326)              NOT NULL,
+    FOREIGN KEY (campus_id) REFERENCES public.campuses (campus_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    UNIQUE (campus_id, entrance_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campuses_boundary ON campuses USING gist (boundary);
+CREATE INDEX IF NOT EXISTS idx_campus_entrances_geog ON campus_entrances USING gist (geog);
+
+-- UW Tacoma, roughly Pacific Ave to Tacoma Ave S between S 17th and S 21st.
+-- The outline is approximate, tighten it with a surveyed boundary when one is available.
+INSERT
+INTO campuses (campus_name, slug, city_id, boundary)
+SELECT 'University of Washington Tacoma',
+       'uw-tacoma',
+       (SELECT city_id FROM cities WHERE city_name = 'Tacoma' LIMIT 1),
+       ST_Multi(ST_GeomFromText('POLYGON((-122.4420 47.2478, -122.4377 47.2478, -122.4366 47.2452,
+                                          -122.4368 47.2428, -122.4395 47.2421, -122.4422 47.2428,
+                                          -122.4420 47.2478))', 4326))::geography
+ON CONFLICT (campus_name) DO NOTHING;
+
+INSERT
+INTO campus_entrances (campus_id, entrance_name, geog)
+SELECT c.campus_id, data.entrance_name, ST_SetSRID(ST_MakePoint(data.lng, data.lat), 4326)::geography
+FROM campuses c
+         CROSS JOIN (VALUES ('Pacific Ave & S 19th St', -122.4368, 47.2449),
+                            ('Pacific Ave & S 17th St', -122.4378, 47.2476),
+                            ('Jefferson Ave & S 21st St', -122.4398, 47.2422),
+                            ('Tacoma Ave S & S 19th St', -122.4421, 47.2450))
+    AS data(entrance_name, lng, lat)
+WHERE c.slug = 'uw-tacoma'
+ON CONFLICT (campus_id, entrance_name) DO NOTHING;
@@ -0,0 +1,14 @@
+-- Center of each neighborhood's geocoded addresses, for the nearest
+-- neighborhoods in /neighborhoods/:name. Needs sql/spatial.sql. The importer
+-- refreshes it after each crime csv, refresh it by hand after other changes
+-- to addresses.
+CREATE MATERIALIZED VIEW IF NOT EXISTS neighborhood_centers AS
+SELECT a.neighborhood_id,
+       ST_Centroid(ST_Collect(l.geog::geometry))::geography AS center
+FROM addresses a
+         JOIN locations l ON a.location_id = l.location_id
+WHERE a.neighborhood_id IS NOT NULL
+GROUP BY a.neighborhood_id;
+
+-- REFRESH ... CONCURRENTLY needs a unique index
+CREATE UNIQUE INDEX IF NOT EXISTS idx_neighborhood_centers_id ON neighborhood_centers (neighborhood_id);
@@ -48,11 +48,11 @@ CREATE TABLE IF NOT EXISTS public.neighborhoods
 (
     neighborhood_id   bigint GENERATED ALWAYS AS IDENTITY NOT NULL PRIMARY KEY,
     neighborhood_name varchar(100)                        NOT NULL,
---     city_id           bigint                              NOT NULL,
+    city_id           bigint                              NOT NULL,
     created_at        timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at        timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP
---     FOREIGN KEY (city_id) REFERENCES public.cities (city_id) ON UPDATE CASCADE ON DELETE RESTRICT,
---     UNIQUE (neighborhood_name, city_id)
+    updated_at        timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (city_id) REFERENCES public.cities (city_id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    UNIQUE (neighborhood_name, city_id)
 );
 
 CREATE TABLE IF NOT EXISTS public.addresses
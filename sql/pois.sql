@@ -0,0 +1,20 @@
+-- Points of interest (transit stops, parking garages, bars, parks, ...)
+-- loaded with `data_parser pois <type> <file>`. Needs sql/spatial.sql.
+CREATE TABLE IF NOT EXISTS public.pois
+(
+    poi_id     bigint GENERATED ALWAYS AS IDENTITY NOT NULL PRIMARY KEY,
+    poi_name   varchar(255)                        NOT NULL,
+    poi_type   varchar(50)                         NOT NULL,
+    latitude   numeric(10, 7)                      NOT NULL,
+    longitude  numeric(10, 7)                      NOT NULL,
+    source     varchar(255),
+    geog       geography(Point, 4326)
+        GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude::float8, latitude::float8), 4326)::geography) STORED,
+    created_at timestamp with time zone            NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (latitude >= -90.0 AND latitude <= 90.0),
+    CHECK (longitude >= -180.0 AND longitude <= 180.0),
+    UNIQUE (poi_type, poi_name, latitude, longitude)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pois_geog ON pois USING gist (geog);
+CREATE INDEX IF NOT EXISTS idx_pois_type ON pois (poi_type);
@@ -0,0 +1,26 @@
+-- Denominators for per capita and per area crime rates.
+-- cities already carries population and area_sq_mi; neighborhoods get the
+-- same columns, filled in by `data_parser denominators <csv>`.
+ALTER TABLE neighborhoods
+    ADD COLUMN IF NOT EXISTS population integer,
+    ADD COLUMN IF NOT EXISTS area_sq_mi numeric(10, 2);
+
+ALTER TABLE neighborhoods
+    DROP CONSTRAINT IF EXISTS neighborhoods_population_check,
+    ADD CONSTRAINT neighborhoods_population_check CHECK (population > 0 OR population IS NULL),
+    DROP CONSTRAINT IF EXISTS neighborhoods_area_sq_mi_check,
+    ADD CONSTRAINT neighborhoods_area_sq_mi_check CHECK (area_sq_mi > 0 OR area_sq_mi IS NULL);
+
+-- The importers write neighborhoods.city_id, older schemas left it out. Fill
+-- it from the city most of each neighborhood's addresses are in.
+ALTER TABLE neighborhoods
+    ADD COLUMN IF NOT EXISTS city_id bigint REFERENCES cities (city_id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+UPDATE neighborhoods n
+SET city_id = a.city_id
+FROM (SELECT neighborhood_id, MODE() WITHIN GROUP (ORDER BY city_id) AS city_id
+      FROM addresses
+      WHERE neighborhood_id IS NOT NULL
+      GROUP BY neighborhood_id) a
+WHERE n.neighborhood_id = a.neighborhood_id
+  AND n.city_id IS NULL;
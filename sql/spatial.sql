@@ -0,0 +1,18 @@
+-- PostGIS geography for spatial filtering (radius, bbox, polygon, nearest)
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+-- kept in sync with latitude/longitude, so importers don't need to change
+ALTER TABLE locations
+    ADD COLUMN IF NOT EXISTS geog geography(Point, 4326)
+        GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude::float8, latitude::float8), 4326)::geography) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_locations_geog ON locations USING gist (geog);
+
+-- replaced by idx_locations_geog
+DROP INDEX IF EXISTS idx_locations_point;
+
+-- lets the planner pick the geog index over partition scans
+CREATE INDEX IF NOT EXISTS idx_addresses_location ON addresses (location_id);
+
+ANALYZE locations;
+ANALYZE addresses;